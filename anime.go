@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Anime is the full record the /anime/{route} endpoint returns.
+type Anime struct {
+	Title       string         `json:"title"`
+	Route       string         `json:"route"`
+	Description string         `json:"description"`
+	Premier     time.Time      `json:"premier"`
+	Season      AnimeSeason    `json:"season"`
+	Genres      []Category     `json:"genres"`
+	Studios     []Category     `json:"studios"`
+	Sources     []Category     `json:"sources"`
+	MediaTypes  []MediaType    `json:"mediaTypes"`
+	Episodes    int            `json:"episodes"`
+	LengthMin   int            `json:"lengthMin"`
+	Status      string         `json:"status"`
+	Names       AnimeNames     `json:"names"`
+	Stats       AnimeStats     `json:"stats"`
+	Relations   AnimeRelations `json:"relations"`
+}
+
+type Category struct {
+	Name  string `json:"name"`
+	Route string `json:"route"`
+}
+
+type AnimeSeason struct {
+	Title  string `json:"title"`
+	Season string `json:"season"`
+	Route  string `json:"route"`
+}
+
+type AnimeNames struct {
+	Romaji       string   `json:"romaji,omitempty"`
+	English      string   `json:"english,omitempty"`
+	Native       string   `json:"native,omitempty"`
+	Abbreviation string   `json:"abbreviation,omitempty"`
+	Synonyms     []string `json:"synonyms,omitempty"`
+}
+
+type AnimeStats struct {
+	AverageScore float64 `json:"averageScore"`
+	RatingCount  int     `json:"ratingCount"`
+	TrackedCount int     `json:"trackedCount"`
+}
+
+// AnimeRelations lists the routes of related entries by kind.
+type AnimeRelations struct {
+	Sequels      []string `json:"sequels,omitempty"`
+	Prequels     []string `json:"prequels,omitempty"`
+	Parents      []string `json:"parents,omitempty"`
+	Alternatives []string `json:"alternatives,omitempty"`
+	Other        []string `json:"other,omitempty"`
+	SideStories  []string `json:"sideStories,omitempty"`
+	Spinoffs     []string `json:"spinoffs,omitempty"`
+}
+
+// FetchAnime fetches the full record of the anime at route.
+func (c *APIClient) FetchAnime(route string) (*Anime, error) {
+	u, err := c.endpoint("/anime/" + url.PathEscape(route))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.do(u, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var anime Anime
+	if err := json.NewDecoder(res.Body).Decode(&anime); err != nil {
+		return nil, err
+	}
+	return &anime, nil
+}
+
+// animeCacheSchemaVersion is bumped whenever animeCacheEntry or Anime
+// changes shape. It is separate from the timetable's, so either cache can
+// change without throwing away the other.
+const animeCacheSchemaVersion = 1
+
+// animeCacheEntry is the envelope a cached anime record is stored in.
+type animeCacheEntry struct {
+	Version   int       `json:"version"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	Anime     *Anime    `json:"anime"`
+}
+
+func getAnimeCacheDir() string {
+	return filepath.Join(getCacheDir(), "anime")
+}
+
+func getAnimeCacheFilePath(route string) string {
+	return filepath.Join(getAnimeCacheDir(), url.PathEscape(route)+".json")
+}
+
+func readAnimeCacheEntry(path string) (*animeCacheEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry animeCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	if entry.Version != animeCacheSchemaVersion || entry.Anime == nil {
+		return nil, errCacheVersion
+	}
+	return &entry, nil
+}
+
+func loadAnimeCache(route string) (*animeCacheEntry, error) {
+	return readAnimeCacheEntry(getAnimeCacheFilePath(route))
+}
+
+// saveAnimeCache writes the record of route and evicts expired records.
+func saveAnimeCache(route string, anime *Anime) error {
+	cacheFile := getAnimeCacheFilePath(route)
+
+	// Create cache directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(animeCacheEntry{
+		Version:   animeCacheSchemaVersion,
+		FetchedAt: time.Now(),
+		Anime:     anime,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(cacheFile, data, 0644); err != nil {
+		return err
+	}
+
+	_, err = evictAnimeCache(time.Now().Add(-cfg.Cache.MaxAge))
+	return err
+}
+
+// evictAnimeCache removes records fetched before cutoff, along with records
+// that cannot be read by this version, and returns how many it removed.
+func evictAnimeCache(cutoff time.Time) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(getAnimeCacheDir(), "*.json"))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, path := range paths {
+		entry, err := readAnimeCacheEntry(path)
+		if err == nil && !entry.FetchedAt.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// loadAnime returns the anime at route from a fresh cache or the API,
+// falling back to a stale cache when the API cannot be reached.
+func loadAnime(client *APIClient, route string) (*Anime, error) {
+	cached, cacheErr := loadAnimeCache(route)
+	if cacheErr == nil && time.Since(cached.FetchedAt) < cfg.Cache.AnimeTTL {
+		return cached.Anime, nil
+	}
+
+	anime, err := client.FetchAnime(route)
+	if err != nil {
+		if cacheErr == nil {
+			return cached.Anime, nil
+		}
+		return nil, err
+	}
+
+	// A failed cache write only costs a refetch next time
+	_ = saveAnimeCache(route, anime)
+	return anime, nil
+}
+
+// animeDetailMsg delivers the full record for the detail view.
+type animeDetailMsg struct {
+	route string
+	anime *Anime
+	err   error
+}
+
+func fetchAnimeCmd(client *APIClient, route string) tea.Cmd {
+	return func() tea.Msg {
+		anime, err := loadAnime(client, route)
+		return animeDetailMsg{route: route, anime: anime, err: err}
+	}
+}
+
+// synopsis returns the description as plain text, the API sends it with
+// HTML line breaks and markup.
+func (a Anime) synopsis() string {
+	text := strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n").Replace(a.Description)
+
+	var b strings.Builder
+	inTag := false
+	for _, r := range text {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>' && inTag:
+			inTag = false
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// relatedRoutes flattens the relations into "kind: route, route" lines.
+func (a Anime) relatedRoutes() []string {
+	kinds := []struct {
+		name   string
+		routes []string
+	}{
+		{"Prequels", a.Relations.Prequels},
+		{"Sequels", a.Relations.Sequels},
+		{"Parents", a.Relations.Parents},
+		{"Side stories", a.Relations.SideStories},
+		{"Spinoffs", a.Relations.Spinoffs},
+		{"Alternatives", a.Relations.Alternatives},
+		{"Other", a.Relations.Other},
+	}
+
+	var lines []string
+	for _, kind := range kinds {
+		if len(kind.routes) > 0 {
+			lines = append(lines, kind.name+": "+strings.Join(kind.routes, ", "))
+		}
+	}
+	return lines
+}
+
+func categoryNames(categories []Category) string {
+	names := make([]string, len(categories))
+	for i, category := range categories {
+		names[i] = category.Name
+	}
+	return strings.Join(names, ", ")
+}
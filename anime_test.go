@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEvictAnimeCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	for _, route := range []string{"frieren", "one-piece"} {
+		if err := saveAnimeCache(route, &Anime{Route: route}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(getAnimeCacheDir(), "broken.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the unreadable record is older than any cutoff in the past
+	removed, err := evictAnimeCache(time.Now().Add(-time.Hour))
+	if err != nil || removed != 1 {
+		t.Fatalf("evictAnimeCache removed %d, %v, want 1", removed, err)
+	}
+	if _, err := loadAnimeCache("frieren"); err != nil {
+		t.Errorf("fresh record evicted: %v", err)
+	}
+
+	removed, err = evictAnimeCache(time.Now().Add(time.Second))
+	if err != nil || removed != 2 {
+		t.Fatalf("evictAnimeCache removed %d, %v, want 2", removed, err)
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultBaseURL = "https://animeschedule.net/api/v3"
+
+// RetryPolicy decides how often a failed request is attempted again.
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt, 1 disables retries
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between two attempts
+	MaxDelay time.Duration
+}
+
+// backoff returns the jittered wait before retry number attempt, counting
+// from 1. The delay doubles every attempt up to MaxDelay, and a random half
+// of it is dropped so clients do not retry in lockstep.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+var (
+	// ErrUnauthorized means the API token is missing, invalid or revoked
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrRateLimited means too many requests were made in a short time
+	ErrRateLimited = errors.New("rate limited")
+	// ErrServer means the API failed on its side
+	ErrServer = errors.New("server error")
+	// ErrNotFound means the requested resource does not exist
+	ErrNotFound = errors.New("not found")
+)
+
+// APIError is an unsuccessful API response. It unwraps to one of the Err
+// values above when the status code maps to one.
+type APIError struct {
+	StatusCode int
+	Status     string
+	Body       string
+	// RetryAfter is how long the API asked to wait, on 429 responses
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Body == "" {
+		return "API request failed: " + e.Status
+	}
+	return fmt.Sprintf("API request failed: %s, response: %s", e.Status, e.Body)
+}
+
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode >= 500:
+		return ErrServer
+	}
+	return nil
+}
+
+// newAPIError builds the error for an unsuccessful response and closes its
+// body.
+func newAPIError(res *http.Response) *APIError {
+	defer res.Body.Close()
+
+	// Error pages can be huge, keep enough to be useful in a message
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	return &APIError{
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+		Body:       strings.TrimSpace(string(body)),
+		RetryAfter: retryAfter(res.Header, time.Now()),
+	}
+}
+
+// retryAfter reads how long to wait from Retry-After, given in seconds or
+// as an HTTP date, falling back to the X-RateLimit-Reset epoch timestamp.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(value); err == nil && date.After(now) {
+			return date.Sub(now)
+		}
+	}
+	if value := header.Get("X-RateLimit-Reset"); value != "" {
+		if epoch, err := strconv.ParseInt(value, 10, 64); err == nil && time.Unix(epoch, 0).After(now) {
+			return time.Unix(epoch, 0).Sub(now)
+		}
+	}
+	return 0
+}
+
+// friendlyError turns API errors into messages for people rather than logs.
+func friendlyError(err error) string {
+	var apiErr *APIError
+	errors.As(err, &apiErr)
+
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		return "animeschedule.net rejected the API token, check ANIMESCHEDULE_TOKEN"
+	case errors.Is(err, ErrRateLimited) && apiErr.RetryAfter > 0:
+		return fmt.Sprintf("animeschedule.net is rate limiting requests, try again in %s", apiErr.RetryAfter.Round(time.Second))
+	case errors.Is(err, ErrRateLimited):
+		return "animeschedule.net is rate limiting requests, try again shortly"
+	case errors.Is(err, ErrServer):
+		return fmt.Sprintf("animeschedule.net is having trouble (%s), try again later", apiErr.Status)
+	case errors.Is(err, ErrNotFound):
+		return "animeschedule.net has no such entry"
+	}
+	return err.Error()
+}
+
+// APIClient talks to the animeschedule.net v3 API. Every field can be
+// replaced, so tests can point it at an httptest.Server.
+type APIClient struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+	UserAgent  string
+	Retry      RetryPolicy
+
+	// sleep waits between attempts, tests replace it to run instantly
+	sleep func(time.Duration)
+}
+
+// NewAPIClient returns a client for the configured API with token.
+func NewAPIClient(token string) *APIClient {
+	return &APIClient{
+		BaseURL:    cfg.API.BaseURL,
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: cfg.API.Timeout},
+		UserAgent:  "baka (+https://github.com/Neel-shetty/baka)",
+		Retry: RetryPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   500 * time.Millisecond,
+			MaxDelay:    10 * time.Second,
+		},
+		sleep: time.Sleep,
+	}
+}
+
+// timetableResponse is the outcome of a timetables request. NotModified is
+// set when the server answered 304 to the ETag sent along, and Timetables is
+// empty then.
+type timetableResponse struct {
+	URL         string
+	ETag        string
+	NotModified bool
+	Timetables  []AnimeTimetable
+}
+
+// endpoint resolves path against the base URL, which may end in a slash.
+func (c *APIClient) endpoint(path string) (*url.URL, error) {
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %v", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	return u, nil
+}
+
+// do sends an authenticated GET request. Timeouts and 5xx responses are
+// retried with exponential backoff, and 429 responses after the wait the API
+// asks for, as long as the retry policy allows. Any other unsuccessful
+// status is returned as an *APIError, except 304 which callers handle.
+func (c *APIClient) do(u *url.URL, header http.Header) (*http.Response, error) {
+	if c.Token == "" {
+		return nil, errors.New("ANIMESCHEDULE_TOKEN environment variable not set")
+	}
+
+	sleep := c.sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+
+	attempts := max(c.Retry.MaxAttempts, 1)
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			wait := c.Retry.backoff(attempt - 1)
+
+			var apiErr *APIError
+			if errors.As(lastErr, &apiErr) && apiErr.RetryAfter > 0 {
+				// Waiting longer than the policy allows would just hang
+				if apiErr.RetryAfter > c.Retry.MaxDelay {
+					break
+				}
+				wait = apiErr.RetryAfter
+			}
+			sleep(wait)
+		}
+
+		req, err := http.NewRequest("GET", u.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %v", err)
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", c.UserAgent)
+
+		res, err := c.HTTPClient.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("request failed: %w", err)
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return nil, lastErr
+		}
+
+		if res.StatusCode < 400 {
+			return res, nil
+		}
+
+		lastErr = newAPIError(res)
+		if res.StatusCode != http.StatusTooManyRequests && res.StatusCode < 500 {
+			return nil, lastErr
+		}
+	}
+
+	return nil, lastErr
+}
+
+// FetchTimetables fetches one week of the timetable. The query is validated
+// before anything is sent. When etag is set the request is conditional and
+// may come back NotModified.
+func (c *APIClient) FetchTimetables(query TimetableQuery, etag string) (*timetableResponse, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	url, err := c.endpoint(query.path())
+	if err != nil {
+		return nil, err
+	}
+	url.RawQuery = query.values().Encode()
+
+	header := http.Header{}
+	if etag != "" {
+		header.Set("If-None-Match", etag)
+	}
+
+	res, err := c.do(url, header)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	response := &timetableResponse{URL: url.String(), ETag: res.Header.Get("ETag")}
+	if res.StatusCode == http.StatusNotModified && etag != "" {
+		response.NotModified = true
+		return response, nil
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: res.StatusCode, Status: res.Status}
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&response.Timetables); err != nil {
+		return nil, fmt.Errorf("failed to decode json response: %v", err)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client for a server that answers with handler,
+// and the waits the client slept through instead of sleeping.
+func newTestClient(t *testing.T, handler http.HandlerFunc) (*APIClient, *[]time.Duration) {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	slept := &[]time.Duration{}
+	client := &APIClient{
+		BaseURL:    srv.URL,
+		Token:      "token",
+		HTTPClient: srv.Client(),
+		UserAgent:  "baka-test",
+		Retry: RetryPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   500 * time.Millisecond,
+			MaxDelay:    10 * time.Second,
+		},
+		sleep: func(d time.Duration) { *slept = append(*slept, d) },
+	}
+	return client, slept
+}
+
+// doTimetables sends one request to the timetables endpoint through do.
+func doTimetables(t *testing.T, client *APIClient) error {
+	t.Helper()
+
+	u, err := client.endpoint("/timetables")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.do(u, nil)
+	if err == nil {
+		res.Body.Close()
+	}
+	return err
+}
+
+// statusHandler answers every request with status, counting them in calls.
+func statusHandler(calls *atomic.Int32, status int, header http.Header) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(status)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	for attempt, full := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 60: 5 * time.Second} {
+		for range 20 {
+			if wait := p.backoff(attempt); wait < full/2 || wait > full {
+				t.Errorf("backoff(%d) = %v, want between %v and %v", attempt, wait, full/2, full)
+			}
+		}
+	}
+}
+
+func TestDoRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	client, slept := newTestClient(t, statusHandler(&calls, http.StatusServiceUnavailable, nil))
+
+	err := doTimetables(t, client)
+	if !errors.Is(err, ErrServer) {
+		t.Errorf("err = %v, want ErrServer", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("sent %d requests, want MaxAttempts 3", calls.Load())
+	}
+	if len(*slept) != 2 {
+		t.Errorf("slept %v, want a wait before each retry", *slept)
+	}
+}
+
+func TestDoWaitsForRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	client, slept := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	})
+
+	if err := doTimetables(t, client); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("sent %d requests, want 2", calls.Load())
+	}
+	if want := []time.Duration{7 * time.Second}; !slices.Equal(*slept, want) {
+		t.Errorf("slept %v, want %v", *slept, want)
+	}
+}
+
+func TestDoGivesUpWhenRetryAfterExceedsMaxDelay(t *testing.T) {
+	var calls atomic.Int32
+	header := http.Header{"Retry-After": {"60"}}
+	client, slept := newTestClient(t, statusHandler(&calls, http.StatusTooManyRequests, header))
+
+	err := doTimetables(t, client)
+	var apiErr *APIError
+	if !errors.Is(err, ErrRateLimited) || !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	if apiErr.RetryAfter != time.Minute {
+		t.Errorf("RetryAfter = %v, want 1m", apiErr.RetryAfter)
+	}
+	if calls.Load() != 1 || len(*slept) != 0 {
+		t.Errorf("sent %d requests and slept %v, want one request and no wait", calls.Load(), *slept)
+	}
+}
+
+func TestDoDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	client, slept := newTestClient(t, statusHandler(&calls, http.StatusBadRequest, nil))
+
+	err := doTimetables(t, client)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("err = %v, want a 400 APIError", err)
+	}
+	for _, sentinel := range []error{ErrUnauthorized, ErrRateLimited, ErrServer, ErrNotFound} {
+		if errors.Is(err, sentinel) {
+			t.Errorf("err = %v, should not be %v", err, sentinel)
+		}
+	}
+	if calls.Load() != 1 || len(*slept) != 0 {
+		t.Errorf("sent %d requests and slept %v, want one request and no wait", calls.Load(), *slept)
+	}
+}
+
+func TestDoMapsUnauthorized(t *testing.T) {
+	var calls atomic.Int32
+	client, _ := newTestClient(t, statusHandler(&calls, http.StatusUnauthorized, nil))
+
+	if err := doTimetables(t, client); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("err = %v, want ErrUnauthorized", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("sent %d requests, want 1", calls.Load())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"seconds", http.Header{"Retry-After": {"30"}}, 30 * time.Second},
+		{"date", http.Header{"Retry-After": {now.Add(2 * time.Minute).Format(http.TimeFormat)}}, 2 * time.Minute},
+		{"past date", http.Header{"Retry-After": {now.Add(-time.Minute).Format(http.TimeFormat)}}, 0},
+		{"reset", http.Header{"X-Ratelimit-Reset": {strconv.FormatInt(now.Add(90*time.Second).Unix(), 10)}}, 90 * time.Second},
+		{"retry after wins", http.Header{"Retry-After": {"5"}, "X-Ratelimit-Reset": {strconv.FormatInt(now.Add(time.Hour).Unix(), 10)}}, 5 * time.Second},
+		{"none", http.Header{}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := retryAfter(tt.header, now); got != tt.want {
+			t.Errorf("%s: retryAfter = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFetchTimetablesBuildsPathAndQuery(t *testing.T) {
+	tests := []struct {
+		base     string
+		query    TimetableQuery
+		path     string
+		rawQuery string
+	}{
+		{"/api/v3", TimetableQuery{AirType: AirTypeDub, Week: 42, Year: 2026, TZ: "Europe/Berlin"}, "/api/v3/timetables/dub", "tz=Europe%2FBerlin&week=42&year=2026"},
+		// A base URL ending in a slash must not double it
+		{"/api/v3/", TimetableQuery{AirType: AirTypeSub, Week: 1, Year: 2027}, "/api/v3/timetables/sub", "week=1&year=2027"},
+		{"/api/v3/", TimetableQuery{}, "/api/v3/timetables", ""},
+	}
+
+	for _, tt := range tests {
+		var got *http.Request
+		client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			got = r
+			w.Write([]byte("[]"))
+		})
+		client.BaseURL += tt.base
+
+		if _, err := client.FetchTimetables(tt.query, ""); err != nil {
+			t.Fatalf("%+v: %v", tt.query, err)
+		}
+		if got.URL.Path != tt.path || got.URL.RawQuery != tt.rawQuery {
+			t.Errorf("%s %+v requested %s?%s, want %s?%s", tt.base, tt.query, got.URL.Path, got.URL.RawQuery, tt.path, tt.rawQuery)
+		}
+		if auth := got.Header.Get("Authorization"); auth != "Bearer token" {
+			t.Errorf("Authorization = %q, want the bearer token", auth)
+		}
+	}
+}
+
+func TestFetchAndCacheTimetableReusesCacheOnNotModified(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	episodes := []AnimeTimetable{testEpisode("frieren", 5, notifyTestStart)}
+	var etags []string
+	client, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		etags = append(etags, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		json.NewEncoder(w).Encode(episodes)
+	})
+	query := TimetableQuery{AirType: AirTypeSub, Week: 42, Year: 2026, TZ: "Etc/UTC"}
+
+	for range 2 {
+		timetables, err := fetchAndCacheTimetable(client, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(timetables) != 1 || timetables[0].Route != "frieren" {
+			t.Errorf("timetables = %+v, want the cached frieren episode", timetables)
+		}
+	}
+	if want := []string{"", `"v1"`}; !slices.Equal(etags, want) {
+		t.Errorf("sent If-None-Match %q, want %q", etags, want)
+	}
+
+	entry, err := loadTimetableCache(timetableCacheKey(query))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.ETag != `"v1"` || len(entry.Timetables) != 1 || !strings.HasSuffix(entry.URL, "/timetables/sub?tz=Etc%2FUTC&week=42&year=2026") {
+		t.Errorf("cache entry = %+v, want the ETag and timetables kept", entry)
+	}
+}
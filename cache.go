@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// cacheSchemaVersion is bumped whenever timetableCacheEntry changes shape.
+// Entries written with another version are treated as missing.
+const cacheSchemaVersion = 1
+
+var errCacheVersion = errors.New("cache entry has an old schema version")
+
+// timetableCacheEntry is the envelope a cached timetable is stored in.
+type timetableCacheEntry struct {
+	Version    int              `json:"version"`
+	Key        string           `json:"key"`
+	URL        string           `json:"url"`
+	ETag       string           `json:"etag,omitempty"`
+	FetchedAt  time.Time        `json:"fetchedAt"`
+	Timetables []AnimeTimetable `json:"timetables"`
+}
+
+func getCacheDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ".baka-cache" // fallback to current directory
+	}
+	return filepath.Join(homeDir, ".cache", "baka")
+}
+
+func getTimetableCacheDir() string {
+	return filepath.Join(getCacheDir(), "timetables")
+}
+
+// timetableCacheKey identifies a timetable by every parameter that changes
+// the API response, so that no entry is served for another query.
+func timetableCacheKey(query TimetableQuery) string {
+	return fmt.Sprintf("%s/%d-W%02d/%s", query.AirType, query.Year, query.Week, query.TZ)
+}
+
+func getCacheFilePath(key string) string {
+	return filepath.Join(getTimetableCacheDir(), strings.ReplaceAll(key, "/", "_")+".json")
+}
+
+func readCacheEntry(path string) (*timetableCacheEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry timetableCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	if entry.Version != cacheSchemaVersion {
+		return nil, errCacheVersion
+	}
+
+	return &entry, nil
+}
+
+func loadTimetableCache(key string) (*timetableCacheEntry, error) {
+	return readCacheEntry(getCacheFilePath(key))
+}
+
+// saveTimetableCache writes entry and evicts expired entries.
+func saveTimetableCache(entry *timetableCacheEntry) error {
+	cacheFile := getCacheFilePath(entry.Key)
+
+	// Create cache directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err != nil {
+		return err
+	}
+
+	entry.Version = cacheSchemaVersion
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(cacheFile, data, 0644); err != nil {
+		return err
+	}
+
+	_, err = evictTimetableCache(time.Now().Add(-cfg.Cache.MaxAge))
+	return err
+}
+
+// legacyCachePattern matches the timetable files written before the cache
+// was keyed by query, straight into the cache directory.
+const legacyCachePattern = "anime_schedule*.json"
+
+// evictTimetableCache removes entries fetched before cutoff, along with
+// entries that cannot be read by this version, and returns how many it
+// removed.
+func evictTimetableCache(cutoff time.Time) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(getTimetableCacheDir(), "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	legacy, err := filepath.Glob(filepath.Join(getCacheDir(), legacyCachePattern))
+	if err != nil {
+		return 0, err
+	}
+	paths = append(paths, legacy...)
+
+	removed := 0
+	for _, path := range paths {
+		// Legacy files never parse as an entry, so they are always removed
+		entry, err := readCacheEntry(path)
+		if err == nil && !entry.FetchedAt.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func runCache(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: baka cache ls|clear")
+	}
+
+	switch args[0] {
+	case "ls":
+		return listCache(stdout)
+	case "clear":
+		// Everything fetched before now is evicted
+		cutoff := time.Now().Add(time.Second)
+		removed, err := evictTimetableCache(cutoff)
+		if err != nil {
+			return err
+		}
+		removedAnime, err := evictAnimeCache(cutoff)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Removed %d cache entries\n", removed+removedAnime)
+		return nil
+	default:
+		return fmt.Errorf("unknown cache command %q (want ls or clear)", args[0])
+	}
+}
+
+// cacheRow is one entry listed by `baka cache ls`.
+type cacheRow struct {
+	key       string
+	fetchedAt time.Time
+	fresh     bool
+	shows     int
+	etag      string
+}
+
+// listCache lists the cached timetables and anime records, the most
+// recently fetched first.
+func listCache(w io.Writer) error {
+	paths, err := filepath.Glob(filepath.Join(getTimetableCacheDir(), "*.json"))
+	if err != nil {
+		return err
+	}
+	animePaths, err := filepath.Glob(filepath.Join(getAnimeCacheDir(), "*.json"))
+	if err != nil {
+		return err
+	}
+
+	var rows []cacheRow
+	for _, path := range paths {
+		if entry, err := readCacheEntry(path); err == nil {
+			rows = append(rows, cacheRow{
+				key:       entry.Key,
+				fetchedAt: entry.FetchedAt,
+				fresh:     isCacheFresh(entry.FetchedAt),
+				shows:     len(entry.Timetables),
+				etag:      entry.ETag,
+			})
+		}
+	}
+	for _, path := range animePaths {
+		if entry, err := readAnimeCacheEntry(path); err == nil {
+			rows = append(rows, cacheRow{
+				key:       "anime/" + entry.Anime.Route,
+				fetchedAt: entry.FetchedAt,
+				fresh:     time.Since(entry.FetchedAt) < cfg.Cache.AnimeTTL,
+				shows:     1,
+			})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].fetchedAt.After(rows[j].fetchedAt)
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tFETCHED\tSTATUS\tSHOWS\tETAG")
+	for _, row := range rows {
+		status := "fresh"
+		if !row.fresh {
+			status = "stale"
+		}
+		etag := row.etag
+		if etag == "" {
+			etag = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n",
+			row.key,
+			row.fetchedAt.Local().Format("2006-01-02 15:04"),
+			status,
+			row.shows,
+			etag)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// timetableFlags are the flags shared by every subcommand that reads the
+// timetable.
+type timetableFlags struct {
+	airType string
+	year    int
+	week    int
+	mine    bool
+}
+
+func addTimetableFlags(fs *flag.FlagSet) *timetableFlags {
+	year, week := time.Now().ISOWeek()
+	f := &timetableFlags{}
+	fs.StringVar(&f.airType, "air", cfg.API.AirType, "air type: "+strings.Join(airTypes, ", "))
+	fs.IntVar(&f.year, "year", year, "ISO year of the timetable")
+	fs.IntVar(&f.week, "week", week, "ISO week of the timetable")
+	fs.BoolVar(&f.mine, "mine", false, "only list shows on the watchlist")
+	return f
+}
+
+// load fetches the timetable through the same cached path as the TUI and
+// drops hidden shows, plus unwatched ones when --mine is set.
+func (f *timetableFlags) load(client *APIClient) ([]AnimeTimetable, error) {
+	timetables, err := loadTimetable(client, timetableQuery(f.year, f.week, f.airType))
+	if err != nil {
+		return nil, err
+	}
+
+	watchlist, err := loadWatchlist()
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := loadHiddenList()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []AnimeTimetable
+	for _, anime := range timetables {
+		if hidden.has(anime.Route) || (f.mine && !watchlist.has(anime.Route)) {
+			continue
+		}
+		result = append(result, anime)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].EpisodeDate.Before(result[j].EpisodeDate)
+	})
+	return result, nil
+}
+
+// usage is printed for -h, --help and help.
+const usage = `Usage: baka [command] [flags]
+
+Without a command, baka opens the weekly timetable.
+
+Commands:
+  today     list today's episodes
+  week      list the episodes of a week
+  search    search all anime by title
+  export    export the timetable to a file or stdout
+  notify    send desktop notifications before watched episodes
+  config    show the configuration
+  cache     list or clear the cache (ls|clear)
+
+Run baka <command> -h for the flags of a command.
+`
+
+// runCommand runs the non-interactive subcommand named by args[0]. It
+// reports false when there is no subcommand and the TUI should start.
+func runCommand(client *APIClient, args []string, stdout io.Writer) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	switch args[0] {
+	case "today":
+		return true, runToday(client, args[1:], stdout)
+	case "week":
+		return true, runWeek(client, args[1:], stdout)
+	case "search":
+		return true, runSearch(client, args[1:], stdout)
+	case "export":
+		return true, runExport(client, args[1:], stdout)
+	case "config":
+		return true, runConfig(args[1:], stdout)
+	case "cache":
+		return true, runCache(args[1:], stdout)
+	case "notify":
+		return true, runNotify(client, args[1:], stdout)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return true, nil
+	default:
+		return true, fmt.Errorf("unknown command %q (want today, week, search, export, notify, config or cache)", args[0])
+	}
+}
+
+func runToday(client *APIClient, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("today", flag.ContinueOnError)
+	tf := addTimetableFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	timetables, err := tf.load(client)
+	if err != nil {
+		return err
+	}
+
+	today := time.Now().Weekday()
+	return printTimetable(stdout, filterByWeekday(timetables, today))
+}
+
+func runWeek(client *APIClient, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("week", flag.ContinueOnError)
+	tf := addTimetableFlags(fs)
+	day := fs.String("day", "", "only list one weekday, e.g. fri")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	weekday := time.Weekday(-1)
+	if *day != "" {
+		var err error
+		if weekday, err = parseWeekday(*day); err != nil {
+			return err
+		}
+	}
+
+	timetables, err := tf.load(client)
+	if err != nil {
+		return err
+	}
+
+	if weekday >= 0 {
+		timetables = filterByWeekday(timetables, weekday)
+	}
+
+	return printTimetable(stdout, timetables)
+}
+
+func runSearch(client *APIClient, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	tf := addTimetableFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	query := strings.Join(fs.Args(), " ")
+	if query == "" {
+		return errors.New("usage: baka search [flags] <query>")
+	}
+
+	timetables, err := tf.load(client)
+	if err != nil {
+		return err
+	}
+
+	targets := make([]string, len(timetables))
+	for i, anime := range timetables {
+		targets[i] = animeItem{anime: anime}.FilterValue()
+	}
+
+	var matches []AnimeTimetable
+	for _, rank := range fuzzyFilter(query, targets) {
+		matches = append(matches, timetables[rank.Index])
+	}
+
+	return printTimetable(stdout, matches)
+}
+
+func filterByWeekday(timetables []AnimeTimetable, day time.Weekday) []AnimeTimetable {
+	var result []AnimeTimetable
+	for _, anime := range timetables {
+		if anime.EpisodeDate.Weekday() == day {
+			result = append(result, anime)
+		}
+	}
+	return result
+}
+
+// parseWeekday accepts a full weekday name or any prefix of at least two
+// letters, case insensitively.
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) >= 2 {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.HasPrefix(strings.ToLower(day.String()), s) {
+				return day, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", s)
+}
+
+// printTimetable writes timetables as a plain text table.
+func printTimetable(w io.Writer, timetables []AnimeTimetable) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DAY\tTIME\tEPISODE\tAIR\tTITLE")
+	for _, anime := range timetables {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n",
+			anime.EpisodeDate.Format("Mon"),
+			anime.EpisodeDate.Format("15:04"),
+			anime.EpisodeNumber,
+			anime.AirType,
+			strings.TrimSpace(anime.Title))
+	}
+	return tw.Flush()
+}
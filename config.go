@@ -0,0 +1,433 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// config holds every user tunable setting. defaultConfig has the values used
+// when the config file does not set them.
+type config struct {
+	API    apiConfig
+	Cache  cacheConfig
+	UI     uiConfig
+	Notify notifyConfig
+	Keys   keysConfig
+}
+
+type apiConfig struct {
+	BaseURL          string
+	AirType          string
+	Timeout          time.Duration
+	Timezone         string
+	FallbackTimezone string
+}
+
+type cacheConfig struct {
+	TTL      time.Duration
+	MaxAge   time.Duration
+	AnimeTTL time.Duration
+}
+
+type uiConfig struct {
+	TitleWidth       int
+	TitleForeground  string
+	TitleBackground  string
+	DayBorder        string
+	FocusedDayBorder string
+	ExportFormat     string
+}
+
+type notifyConfig struct {
+	Lead     time.Duration
+	Notifier string
+	Command  string
+}
+
+// keysConfig picks a preset from keyPresets and binds single actions on top
+// of it. Use keys to look up what an action is bound to.
+type keysConfig struct {
+	Preset   string
+	Bindings map[string][]string
+}
+
+func defaultConfig() config {
+	return config{
+		API: apiConfig{
+			BaseURL:          defaultBaseURL,
+			AirType:          "sub",
+			Timeout:          10 * time.Second,
+			FallbackTimezone: "Asia/Kolkata",
+		},
+		Cache: cacheConfig{
+			TTL:      time.Hour,
+			MaxAge:   30 * 24 * time.Hour,
+			AnimeTTL: 7 * 24 * time.Hour,
+		},
+		UI: uiConfig{
+			TitleWidth:       50,
+			TitleForeground:  "#FFFDF5",
+			TitleBackground:  "#25A065",
+			DayBorder:        "62",
+			FocusedDayBorder: "205",
+			ExportFormat:     "ics",
+		},
+		Notify: notifyConfig{
+			Lead:     10 * time.Minute,
+			Notifier: "auto",
+		},
+		Keys: keysConfig{
+			Preset: "default",
+		},
+	}
+}
+
+// cfg is the effective configuration, loaded once at startup.
+var cfg = defaultConfig()
+
+// configField binds a dotted config key to a field of config.
+type configField struct {
+	key string
+	get func(c *config) any
+	set func(c *config, v any) error
+}
+
+var colorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{6}|#[0-9a-fA-F]{3}|[0-9]{1,3})$`)
+
+// configFields lists every supported key, in the order `baka config`
+// prints them.
+var configFields = append([]configField{
+	stringField("api.base_url", func(c *config) *string { return &c.API.BaseURL }, validBaseURL),
+	stringField("api.air_type", func(c *config) *string { return &c.API.AirType }, oneOf(airTypes)),
+	durationField("api.timeout", func(c *config) *time.Duration { return &c.API.Timeout }),
+	stringField("api.timezone", func(c *config) *string { return &c.API.Timezone }, validTimezone(true)),
+	stringField("api.fallback_timezone", func(c *config) *string { return &c.API.FallbackTimezone }, validTimezone(false)),
+	durationField("cache.ttl", func(c *config) *time.Duration { return &c.Cache.TTL }),
+	durationField("cache.max_age", func(c *config) *time.Duration { return &c.Cache.MaxAge }),
+	durationField("cache.anime_ttl", func(c *config) *time.Duration { return &c.Cache.AnimeTTL }),
+	intField("ui.title_width", func(c *config) *int { return &c.UI.TitleWidth }, 10, 200),
+	stringField("ui.title_foreground", func(c *config) *string { return &c.UI.TitleForeground }, validColor),
+	stringField("ui.title_background", func(c *config) *string { return &c.UI.TitleBackground }, validColor),
+	stringField("ui.day_border", func(c *config) *string { return &c.UI.DayBorder }, validColor),
+	stringField("ui.focused_day_border", func(c *config) *string { return &c.UI.FocusedDayBorder }, validColor),
+	stringField("ui.export_format", func(c *config) *string { return &c.UI.ExportFormat }, oneOf(exportFormats)),
+	durationField("notify.lead", func(c *config) *time.Duration { return &c.Notify.Lead }),
+	stringField("notify.notifier", func(c *config) *string { return &c.Notify.Notifier }, oneOf(notifierKinds)),
+	stringField("notify.command", func(c *config) *string { return &c.Notify.Command }, anyString),
+	stringField("keys.preset", func(c *config) *string { return &c.Keys.Preset }, oneOf(keyPresetNames)),
+}, keyFields()...)
+
+func stringField(key string, field func(c *config) *string, validate func(string) error) configField {
+	return configField{
+		key: key,
+		get: func(c *config) any { return *field(c) },
+		set: func(c *config, v any) error {
+			s, ok := v.(string)
+			if !ok {
+				return errors.New("expected a string")
+			}
+			if err := validate(s); err != nil {
+				return err
+			}
+			*field(c) = s
+			return nil
+		},
+	}
+}
+
+func durationField(key string, field func(c *config) *time.Duration) configField {
+	return configField{
+		key: key,
+		get: func(c *config) any { return field(c).String() },
+		set: func(c *config, v any) error {
+			s, ok := v.(string)
+			if !ok {
+				return errors.New(`expected a duration string such as "90s" or "1h"`)
+			}
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return fmt.Errorf("invalid duration %q", s)
+			}
+			if d <= 0 {
+				return errors.New("must be positive")
+			}
+			*field(c) = d
+			return nil
+		},
+	}
+}
+
+func intField(key string, field func(c *config) *int, minimum, maximum int) configField {
+	return configField{
+		key: key,
+		get: func(c *config) any { return *field(c) },
+		set: func(c *config, v any) error {
+			n, ok := v.(int64)
+			if !ok {
+				return errors.New("expected an integer")
+			}
+			if n < int64(minimum) || n > int64(maximum) {
+				return fmt.Errorf("must be between %d and %d", minimum, maximum)
+			}
+			*field(c) = int(n)
+			return nil
+		},
+	}
+}
+
+// keysField binds an action to an array of key names, or to a single key
+// given as a string.
+func keysField(action string) configField {
+	return configField{
+		key: "keys." + action,
+		get: func(c *config) any { return c.Keys.keys(action) },
+		set: func(c *config, v any) error {
+			var keys []string
+			switch v := v.(type) {
+			case string:
+				keys = []string{v}
+			case []any:
+				for _, item := range v {
+					s, ok := item.(string)
+					if !ok || s == "" {
+						return errors.New(`expected key names such as ["k", "up"]`)
+					}
+					keys = append(keys, s)
+				}
+			default:
+				return errors.New(`expected key names such as ["k", "up"]`)
+			}
+
+			if len(keys) == 0 {
+				return errors.New("must bind at least one key")
+			}
+			if action == "jump_day" && len(keys) != len(weekdays) {
+				return fmt.Errorf("must bind %d keys, one per day from Monday", len(weekdays))
+			}
+
+			if c.Keys.Bindings == nil {
+				c.Keys.Bindings = map[string][]string{}
+			}
+			c.Keys.Bindings[action] = keys
+			return nil
+		},
+	}
+}
+
+// keyFields returns a keysField for every action in keyActions.
+func keyFields() []configField {
+	fields := make([]configField, len(keyActions))
+	for i, action := range keyActions {
+		fields[i] = keysField(action.name)
+	}
+	return fields
+}
+
+func oneOf(allowed []string) func(string) error {
+	return func(s string) error {
+		if !slices.Contains(allowed, s) {
+			return fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+		}
+		return nil
+	}
+}
+
+func anyString(string) error { return nil }
+
+func validTimezone(allowEmpty bool) func(string) error {
+	return func(s string) error {
+		if s == "" && allowEmpty {
+			return nil
+		}
+		if s == "" {
+			return errors.New("must not be empty")
+		}
+		if _, err := time.LoadLocation(s); err != nil {
+			return fmt.Errorf("unknown IANA timezone %q", s)
+		}
+		return nil
+	}
+}
+
+func validBaseURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL %q, want http(s)://host/path", s)
+	}
+	return nil
+}
+
+func validColor(s string) error {
+	if !colorPattern.MatchString(s) {
+		return fmt.Errorf("invalid color %q, want #RRGGBB or an ANSI color number", s)
+	}
+	if s[0] != '#' {
+		if n, _ := strconv.Atoi(s); n > 255 {
+			return fmt.Errorf("invalid color %q, ANSI colors go up to 255", s)
+		}
+	}
+	return nil
+}
+
+func getConfigPath() string {
+	return getConfigFilePath("config.toml")
+}
+
+// loadConfig reads the config file at path on top of the defaults. A missing
+// file is not an error.
+func loadConfig(path string) (config, error) {
+	c := defaultConfig()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+
+	var tables map[string]toml.Primitive
+	md, err := toml.Decode(string(data), &tables)
+	if err != nil {
+		var perr toml.ParseError
+		if errors.As(err, &perr) {
+			return c, fmt.Errorf("%s:%d: %s", path, perr.Position.Line, perr.Message)
+		}
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// Keys are decoded one at a time, in file order, so that the decoder
+	// reports the line of a key that does not validate
+	sections := map[string]map[string]toml.Primitive{}
+	for _, key := range md.Keys() {
+		value := configValue{config: &c}
+		var prim toml.Primitive
+		switch len(key) {
+		case 1:
+			// Every setting belongs to a section, inline tables included
+			if md.Type(key...) == "Hash" {
+				var section map[string]toml.Primitive
+				if err := md.PrimitiveDecode(tables[key[0]], &section); err != nil {
+					return c, fmt.Errorf("%s: %s: %w", path, key, err)
+				}
+				sections[key[0]] = section
+				continue
+			}
+			prim = tables[key[0]]
+		case 2:
+			if i := slices.IndexFunc(configFields, func(f configField) bool { return f.key == key.String() }); i >= 0 {
+				value.field = &configFields[i]
+			}
+			prim = sections[key[0]][key[1]]
+		default:
+			// Only reached below a known setting, which rejects the table
+			continue
+		}
+
+		if err := md.PrimitiveDecode(prim, value); err != nil {
+			var perr toml.ParseError
+			switch {
+			case !errors.As(err, &perr):
+				return c, fmt.Errorf("%s: %s: %w", path, key, err)
+			case perr.Position.Line == 0:
+				// Table headers have no line
+				return c, fmt.Errorf("%s: %s: %s", path, key, perr.Message)
+			}
+			return c, fmt.Errorf("%s:%d: %s: %s", path, perr.Position.Line, key, perr.Message)
+		}
+	}
+
+	// Keys can only conflict once the preset and every binding are known
+	if err := c.Keys.check(); err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// configValue sets one field of config from the decoded TOML value, which
+// is a string, int64, float64, bool, time.Time, []any or map[string]any.
+type configValue struct {
+	config *config
+	field  *configField
+}
+
+func (v configValue) UnmarshalTOML(value any) error {
+	if v.field == nil {
+		return errors.New("unknown key")
+	}
+	return v.field.set(v.config, value)
+}
+
+// applyConfig makes c the effective configuration and rebuilds the styles
+// that depend on it.
+func applyConfig(c config) {
+	cfg = c
+
+	titleStyle = titleStyle.
+		Foreground(lipgloss.Color(c.UI.TitleForeground)).
+		Background(lipgloss.Color(c.UI.TitleBackground))
+	dayStyle = dayStyle.BorderForeground(lipgloss.Color(c.UI.DayBorder))
+	focusedDayStyle = focusedDayStyle.BorderForeground(lipgloss.Color(c.UI.FocusedDayBorder))
+}
+
+// writeConfig prints c as a config file, grouped by section.
+func writeConfig(w io.Writer, c config) error {
+	section := ""
+	for _, f := range configFields {
+		name, key, _ := strings.Cut(f.key, ".")
+		if name != section {
+			if section != "" {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "[%s]\n", name)
+			section = name
+		}
+
+		var value string
+		switch v := f.get(&c).(type) {
+		case string:
+			value = strconv.Quote(v)
+		case []string:
+			quoted := make([]string, len(v))
+			for i, s := range v {
+				quoted[i] = strconv.Quote(s)
+			}
+			value = "[" + strings.Join(quoted, ", ") + "]"
+		default:
+			value = fmt.Sprint(v)
+		}
+		if _, err := fmt.Fprintf(w, "%s = %s\n", key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runConfig(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path := getConfigPath()
+	if _, err := os.Stat(path); err != nil {
+		fmt.Fprintf(stdout, "# %s not found, showing defaults\n\n", path)
+	} else {
+		fmt.Fprintf(stdout, "# %s\n\n", path)
+	}
+	return writeConfig(stdout, cfg)
+}
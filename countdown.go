@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var airingColor = lipgloss.Color("42")
+
+// episodeLength is how long an episode runs, defaultEpisodeLength when the
+// API does not know.
+func (a AnimeTimetable) episodeLength() time.Duration {
+	if a.LengthMin > 0 {
+		return time.Duration(a.LengthMin) * time.Minute
+	}
+	return defaultEpisodeLength
+}
+
+// isAiring reports whether the episode is on air at now.
+func (a AnimeTimetable) isAiring(now time.Time) bool {
+	if a.isDelayed() || now.Before(a.EpisodeDate) {
+		return false
+	}
+	return now.Before(a.EpisodeDate.Add(a.episodeLength()))
+}
+
+// countdown describes when the episode airs relative to now, such as
+// "in 2h 13m", "airing now" or "aired 40m ago".
+func (a AnimeTimetable) countdown(now time.Time) string {
+	switch {
+	case a.isAiring(now):
+		return "● airing now"
+	case now.Before(a.EpisodeDate):
+		return "in " + formatCountdown(a.EpisodeDate.Sub(now))
+	default:
+		return "aired " + formatCountdown(now.Sub(a.EpisodeDate)) + " ago"
+	}
+}
+
+// formatCountdown rounds d down to minutes and shows its two largest
+// units, leaving out a zero second unit.
+func formatCountdown(d time.Duration) string {
+	d = d.Truncate(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	switch {
+	case days > 0 && hours > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case days > 0:
+		return fmt.Sprintf("%dd", days)
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	case minutes > 0:
+		return fmt.Sprintf("%dm", minutes)
+	default:
+		return "<1m"
+	}
+}
+
+// minuteTickMsg redraws the countdowns.
+type minuteTickMsg time.Time
+
+// minuteTick fires at the start of the next minute, in step with the
+// countdowns, which count whole minutes.
+func minuteTick() tea.Cmd {
+	return tea.Every(time.Minute, func(t time.Time) tea.Msg {
+		return minuteTickMsg(t)
+	})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// delayMode decides where delayed episodes are listed.
+type delayMode int
+
+const (
+	// delayShow lists delayed episodes on their original day with a badge
+	delayShow delayMode = iota
+	// delayShift lists delayed episodes on the day they resume
+	delayShift
+	// delayHide leaves delayed episodes out entirely
+	delayHide
+)
+
+func (d delayMode) next() delayMode {
+	return (d + 1) % 3
+}
+
+func (d delayMode) String() string {
+	switch d {
+	case delayShift:
+		return "Delays shifted"
+	case delayHide:
+		return "Delays hidden"
+	default:
+		return "Delays shown"
+	}
+}
+
+var delayedColor = lipgloss.Color("214")
+
+// isDelayed reports whether the episode is on a break or pushed back.
+func (a AnimeTimetable) isDelayed() bool {
+	if !a.DelayedUntil.IsZero() {
+		return a.DelayedUntil.After(a.EpisodeDate)
+	}
+	return a.DelayedText != ""
+}
+
+// delayBadge describes the delay for the item description.
+func (a AnimeTimetable) delayBadge() string {
+	reason := a.DelayedText
+	if reason == "" {
+		reason = "Delayed"
+	}
+	if !a.DelayedUntil.IsZero() {
+		reason += " until " + a.DelayedUntil.Format("Jan 2")
+	}
+	return "⏸ " + reason
+}
+
+// listedDate returns the date an episode is listed under in the given week
+// for the delay mode, and false when it should not be listed at all.
+func (d delayMode) listedDate(anime AnimeTimetable, weekStart time.Time) (time.Time, bool) {
+	if !anime.isDelayed() {
+		return anime.EpisodeDate, true
+	}
+
+	switch d {
+	case delayHide:
+		return time.Time{}, false
+	case delayShift:
+		// Shows that resume after this week drop out of it
+		if anime.DelayedUntil.IsZero() || !anime.DelayedUntil.Before(weekStart.AddDate(0, 0, 7)) {
+			return time.Time{}, false
+		}
+		return anime.DelayedUntil, true
+	default:
+		return anime.EpisodeDate, true
+	}
+}
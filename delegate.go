@@ -1,13 +1,69 @@
 package main
 
 import (
+	"io"
+	"strings"
+	"time"
+
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
-func newItemDelegate(keys *delegateKeyMap) list.DefaultDelegate {
+// animeDelegate is the default delegate with separate styles for delayed
+// episodes.
+type animeDelegate struct {
+	list.DefaultDelegate
+	delayedStyles list.DefaultItemStyles
+	airingStyles  list.DefaultItemStyles
+}
+
+func (d animeDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	if i, ok := item.(animeItem); ok {
+		if i.anime.isDelayed() {
+			d.Styles = d.delayedStyles
+		} else if i.anime.isAiring(time.Now()) {
+			d.Styles = d.airingStyles
+		}
+		if m.FilterState() != list.Unfiltered {
+			if _, alias, _ := newAliasMatcher(m.FilterValue()).match(i.FilterValue()); alias > 0 {
+				item = aliasMatchItem{animeItem: i, alias: alias}
+			}
+		}
+	}
+	d.DefaultDelegate.Render(w, m, index, item)
+}
+
+// aliasMatchItem shows which alias matched the filter when it was not the
+// display title.
+type aliasMatchItem struct {
+	animeItem
+	alias int
+}
+
+func (i aliasMatchItem) Description() string {
+	name := strings.Split(i.FilterValue(), aliasSeparator)[i.alias]
+	return i.animeItem.Description() + " • " + aliasLabels[i.alias] + ": " + name
+}
+
+// newAnimeList returns a list of anime items that filters with fuzzyFilter.
+// The weekly view renders the help itself, so the list shows none, and the
+// list moves with the configured keys, which leave the days to the weekly
+// view.
+func newAnimeList(items []list.Item, keys *delegateKeyMap, watchlist, hidden *routeSet, width, height int) list.Model {
+	l := list.New(items, newItemDelegate(keys, watchlist, hidden), width, height)
+	l.Filter = fuzzyFilter
+	l.Styles.Title = titleStyle
+	l.SetShowStatusBar(false)
+	l.SetShowHelp(false)
+	setListKeys(&l.KeyMap)
+	// Enables the new bindings that apply with no filter
+	l.SetFilteringEnabled(true)
+	return l
+}
+
+func newItemDelegate(keys *delegateKeyMap, watchlist, hidden *routeSet) animeDelegate {
 	d := list.NewDefaultDelegate()
 
 	// Set consistent spacing for all items
@@ -19,11 +75,8 @@ func newItemDelegate(keys *delegateKeyMap) list.DefaultDelegate {
 	d.Styles.NormalTitle = lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
 
 	d.UpdateFunc = func(msg tea.Msg, m *list.Model) tea.Cmd {
-		var title string
-
-		if i, ok := m.SelectedItem().(animeItem); ok {
-			title = i.Title()
-		} else {
+		i, ok := m.SelectedItem().(animeItem)
+		if !ok {
 			return nil
 		}
 
@@ -31,22 +84,42 @@ func newItemDelegate(keys *delegateKeyMap) list.DefaultDelegate {
 		case tea.KeyMsg:
 			switch {
 			case key.Matches(msg, keys.choose):
-				return m.NewStatusMessage(statusMessageStyle("You chose " + title))
+				return func() tea.Msg { return showDetailMsg{item: i} }
+
+			case key.Matches(msg, keys.open):
+				return func() tea.Msg { return showStreamsMsg{item: i} }
+
+			case key.Matches(msg, keys.watch):
+				watched, ok, status := toggleWatch(m, watchlist, i.anime.Route, i.anime.Title)
+				if !ok {
+					return status
+				}
+				i.watched = watched
+				return tea.Batch(m.SetItem(m.GlobalIndex(), i), status)
 
 			case key.Matches(msg, keys.remove):
-				index := m.Index()
-				m.RemoveItem(index)
-				if len(m.Items()) == 0 {
-					keys.remove.SetEnabled(false)
+				isHidden, err := hidden.toggle(i.anime.Route)
+				if err != nil {
+					return m.NewStatusMessage(statusMessageStyle("Failed to save hidden shows: " + err.Error()))
+				}
+
+				status := "Unhid " + i.anime.Title
+				if isHidden {
+					status = "Hid " + i.anime.Title
 				}
-				return m.NewStatusMessage(statusMessageStyle("Deleted " + title))
+				// Let the weekly model rebuild the list, the show no longer
+				// belongs in the current view either way
+				return tea.Batch(
+					func() tea.Msg { return routesChangedMsg{} },
+					m.NewStatusMessage(statusMessageStyle(status)),
+				)
 			}
 		}
 
 		return nil
 	}
 
-	help := []key.Binding{keys.choose, keys.remove}
+	help := []key.Binding{keys.choose, keys.open, keys.watch, keys.remove}
 
 	d.ShortHelpFunc = func() []key.Binding {
 		return help
@@ -56,11 +129,39 @@ func newItemDelegate(keys *delegateKeyMap) list.DefaultDelegate {
 		return [][]key.Binding{help}
 	}
 
-	return d
+	delayed := d.Styles
+	delayed.NormalTitle = delayed.NormalTitle.Foreground(delayedColor)
+	delayed.NormalDesc = delayed.NormalDesc.Foreground(delayedColor)
+	delayed.SelectedTitle = delayed.SelectedTitle.Foreground(delayedColor).BorderForeground(delayedColor)
+	delayed.SelectedDesc = delayed.SelectedDesc.Foreground(delayedColor).BorderForeground(delayedColor)
+
+	airing := d.Styles
+	airing.NormalDesc = airing.NormalDesc.Foreground(airingColor)
+	airing.SelectedDesc = airing.SelectedDesc.Foreground(airingColor)
+
+	return animeDelegate{DefaultDelegate: d, delayedStyles: delayed, airingStyles: airing}
+}
+
+// toggleWatch adds or removes route from watchlist for the watch key of a
+// list, returning whether it is now watched and the status message that
+// reports the change. ok is false when the watchlist could not be saved.
+func toggleWatch(m *list.Model, watchlist *routeSet, route, title string) (watched, ok bool, status tea.Cmd) {
+	watched, err := watchlist.toggle(route)
+	if err != nil {
+		return watched, false, m.NewStatusMessage(statusMessageStyle("Failed to save watchlist: " + err.Error()))
+	}
+
+	text := "Removed " + title + " from watchlist"
+	if watched {
+		text = "Added " + title + " to watchlist"
+	}
+	return watched, true, m.NewStatusMessage(statusMessageStyle(text))
 }
 
 type delegateKeyMap struct {
 	choose key.Binding
+	open   key.Binding
+	watch  key.Binding
 	remove key.Binding
 }
 
@@ -69,6 +170,8 @@ type delegateKeyMap struct {
 func (d delegateKeyMap) ShortHelp() []key.Binding {
 	return []key.Binding{
 		d.choose,
+		d.open,
+		d.watch,
 		d.remove,
 	}
 }
@@ -79,6 +182,8 @@ func (d delegateKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{
 			d.choose,
+			d.open,
+			d.watch,
 			d.remove,
 		},
 	}
@@ -86,13 +191,9 @@ func (d delegateKeyMap) FullHelp() [][]key.Binding {
 
 func newDelegateKeyMap() *delegateKeyMap {
 	return &delegateKeyMap{
-		choose: key.NewBinding(
-			key.WithKeys("enter"),
-			key.WithHelp("enter", "choose"),
-		),
-		remove: key.NewBinding(
-			key.WithKeys("x", "backspace"),
-			key.WithHelp("x", "delete"),
-		),
+		choose: newKeyBinding("details", "details"),
+		open:   newKeyBinding("open_stream", "open stream"),
+		watch:  newKeyBinding("watch", "watch"),
+		remove: newKeyBinding("hide", "hide/unhide"),
 	}
 }
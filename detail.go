@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	detailStyle = lipgloss.NewStyle().
+			Padding(1, 2).
+			BorderStyle(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("205"))
+
+	detailTitleStyle = lipgloss.NewStyle().
+				Bold(true).
+				Foreground(lipgloss.Color("212"))
+
+	detailLabelStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("241")).
+				Width(14)
+)
+
+// showDetailMsg asks the weekly model to open the detail view for an item.
+type showDetailMsg struct {
+	item animeItem
+}
+
+type streamLink struct {
+	provider string
+	url      string
+}
+
+// links returns the stream providers that have a URL, in a stable order.
+func (s Streams) links() []streamLink {
+	all := []streamLink{
+		{"Crunchyroll", s.Crunchyroll},
+		{"Amazon", s.Amazon},
+		{"Hidive", s.Hidive},
+		{"YouTube", s.Youtube},
+		{"Apple", s.Apple},
+		{"Netflix", s.Netflix},
+		{"Hulu", s.Hulu},
+	}
+
+	var links []streamLink
+	for _, link := range all {
+		if link.url != "" {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// synopsisLines caps the synopsis so the card fits on a normal terminal.
+const synopsisLines = 8
+
+// renderDetail renders every field of anime as a bordered card that is at
+// most width columns wide. full is the record from the anime endpoint, it
+// is nil while loading or when the lookup failed with fullErr.
+func renderDetail(anime AnimeTimetable, full *Anime, fullErr error, width int) string {
+	width = min(width-4, 76)
+
+	var b strings.Builder
+
+	field := func(label, value string) {
+		if value == "" {
+			value = "—"
+		}
+		b.WriteString(detailLabelStyle.Render(label) + value + "\n")
+	}
+
+	title := strings.TrimSpace(anime.Title)
+	if title == "" {
+		title = "Unknown Title"
+	}
+	b.WriteString(detailTitleStyle.Render(title) + "\n\n")
+
+	field("Romaji", anime.Romaji)
+	field("English", anime.English)
+	field("Native", anime.Native)
+
+	episodes := "?"
+	if anime.Episodes > 0 {
+		episodes = fmt.Sprint(anime.Episodes)
+	}
+	// Search results have no upcoming episode
+	if anime.EpisodeDate.IsZero() {
+		field("Episodes", strings.TrimSuffix(episodes, "?"))
+	} else {
+		field("Episode", fmt.Sprintf("%d of %s", anime.EpisodeNumber, episodes))
+		airs := anime.EpisodeDate.Format("Mon Jan 2, 15:04")
+		if !anime.isDelayed() {
+			airs += " (" + anime.countdown(time.Now()) + ")"
+		}
+		field("Airs", airs)
+	}
+
+	delay := ""
+	if anime.isDelayed() {
+		delay = anime.delayBadge()
+	}
+	field("Delay", delay)
+
+	length := ""
+	if anime.LengthMin > 0 {
+		length = fmt.Sprintf("%d min", anime.LengthMin)
+	}
+	field("Length", length)
+	field("Air type", anime.AirType)
+	field("Status", anime.Status)
+	field("Airing", anime.AiringStatus)
+
+	var mediaTypes []string
+	for _, mediaType := range anime.MediaTypes {
+		mediaTypes = append(mediaTypes, mediaType.Name)
+	}
+	field("Media types", strings.Join(mediaTypes, ", "))
+
+	donghua := "No"
+	if anime.Donghua {
+		donghua = "Yes"
+	}
+	field("Donghua", donghua)
+
+	switch {
+	case full != nil:
+		renderAnimeDetail(&b, *full, width)
+	case fullErr != nil:
+		b.WriteString("\n" + detailLabelStyle.Render("Details") + friendlyError(fullErr) + "\n")
+	default:
+		b.WriteString("\n" + detailLabelStyle.Render("Details") + "Loading…\n")
+	}
+
+	b.WriteString("\n" + detailLabelStyle.Render("Streams"))
+	links := anime.Streams.links()
+	if len(links) == 0 {
+		b.WriteString("—")
+	}
+	for i, link := range links {
+		if i > 0 {
+			b.WriteString("\n" + detailLabelStyle.Render(""))
+		}
+		b.WriteString(link.provider + ": " + link.url)
+	}
+
+	return detailStyle.Width(width).Render(b.String())
+}
+
+// renderAnimeDetail writes the fields only the full anime record has.
+func renderAnimeDetail(b *strings.Builder, anime Anime, width int) {
+	field := func(label, value string) {
+		if value == "" {
+			value = "—"
+		}
+		b.WriteString(detailLabelStyle.Render(label) + value + "\n")
+	}
+
+	b.WriteString("\n")
+	field("Season", anime.Season.Title)
+	field("Genres", categoryNames(anime.Genres))
+	field("Studios", categoryNames(anime.Studios))
+	field("Source", categoryNames(anime.Sources))
+
+	score := ""
+	if anime.Stats.RatingCount > 0 {
+		score = fmt.Sprintf("%.1f (%d ratings)", anime.Stats.AverageScore, anime.Stats.RatingCount)
+	}
+	field("Score", score)
+
+	tracked := ""
+	if anime.Stats.TrackedCount > 0 {
+		tracked = fmt.Sprintf("%d users", anime.Stats.TrackedCount)
+	}
+	field("Tracked by", tracked)
+
+	related := anime.relatedRoutes()
+	if len(related) == 0 {
+		field("Related", "")
+	}
+	for i, line := range related {
+		label := ""
+		if i == 0 {
+			label = "Related"
+		}
+		field(label, line)
+	}
+
+	if synopsis := anime.synopsis(); synopsis != "" {
+		// Padding and border take 6 columns of the card
+		text := lipgloss.NewStyle().Width(width - 6).Render(synopsis)
+		lines := strings.Split(text, "\n")
+		if len(lines) > synopsisLines {
+			lines = append(lines[:synopsisLines-1], strings.TrimRight(lines[synopsisLines-1], " ")+" …")
+		}
+		b.WriteString("\n" + strings.Join(lines, "\n") + "\n")
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportFormats are the file formats the timetable can be exported as.
+var exportFormats = []string{"json", "csv", "ics"}
+
+// defaultEpisodeLength is used for calendar events of episodes whose length
+// the API does not know yet.
+const defaultEpisodeLength = 24 * time.Minute
+
+// writeExport writes timetables to w in the given format.
+func writeExport(w io.Writer, format string, timetables []AnimeTimetable) error {
+	switch format {
+	case "json":
+		return writeJSON(w, timetables)
+	case "csv":
+		return writeCSV(w, timetables)
+	case "ics":
+		return writeICS(w, timetables, time.Now())
+	default:
+		return fmt.Errorf("unknown export format %q (want %s)", format, strings.Join(exportFormats, ", "))
+	}
+}
+
+// exportFile writes timetables to a new file at path in the given format.
+func exportFile(path, format string, timetables []AnimeTimetable) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := writeExport(file, format, timetables); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func writeJSON(w io.Writer, timetables []AnimeTimetable) error {
+	if timetables == nil {
+		timetables = []AnimeTimetable{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(timetables)
+}
+
+func writeCSV(w io.Writer, timetables []AnimeTimetable) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"title", "route", "episode", "episode_date", "length_min", "air_type", "status"})
+	for _, anime := range timetables {
+		cw.Write([]string{
+			strings.TrimSpace(anime.Title),
+			anime.Route,
+			strconv.Itoa(anime.EpisodeNumber),
+			anime.EpisodeDate.Format(time.RFC3339),
+			strconv.Itoa(anime.LengthMin),
+			anime.AirType,
+			anime.Status,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeICS writes one iCalendar event per episode, lasting from EpisodeDate
+// for LengthMin minutes. stamp is the creation time recorded in DTSTAMP.
+func writeICS(w io.Writer, timetables []AnimeTimetable, stamp time.Time) error {
+	const icsTime = "20060102T150405Z"
+
+	var b strings.Builder
+	line := func(name, value string) {
+		b.WriteString(foldICSLine(name + ":" + value))
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//baka//anime timetable//EN")
+	line("CALSCALE", "GREGORIAN")
+	for _, anime := range timetables {
+		length := time.Duration(anime.LengthMin) * time.Minute
+		if length <= 0 {
+			length = defaultEpisodeLength
+		}
+		start := anime.EpisodeDate.UTC()
+
+		line("BEGIN", "VEVENT")
+		line("UID", fmt.Sprintf("%s-%d-%s@baka", anime.Route, anime.EpisodeNumber, anime.AirType))
+		line("DTSTAMP", stamp.UTC().Format(icsTime))
+		line("DTSTART", start.Format(icsTime))
+		line("DTEND", start.Add(length).Format(icsTime))
+		line("SUMMARY", escapeICSText(fmt.Sprintf("%s - Episode %d", strings.TrimSpace(anime.Title), anime.EpisodeNumber)))
+		line("DESCRIPTION", escapeICSText(fmt.Sprintf("Air type: %s", anime.AirType)))
+		line("URL", "https://animeschedule.net/anime/"+anime.Route)
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func escapeICSText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// foldICSLine splits a content line into 75 octet chunks as RFC 5545
+// requires, without cutting through a UTF-8 sequence, and terminates it.
+func foldICSLine(s string) string {
+	const limit = 75
+
+	var b strings.Builder
+	width := limit
+	for len(s) > width {
+		cut := width
+		for cut > 0 && !isRuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines spend one octet on the leading space
+		width = limit - 1
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
+
+func runExport(client *APIClient, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	tf := addTimetableFlags(fs)
+	format := fs.String("format", cfg.UI.ExportFormat, "export format: "+strings.Join(exportFormats, ", "))
+	day := fs.String("day", "", "only export one weekday, e.g. fri")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !slices.Contains(exportFormats, *format) {
+		return fmt.Errorf("unknown export format %q (want %s)", *format, strings.Join(exportFormats, ", "))
+	}
+
+	weekday := time.Weekday(-1)
+	if *day != "" {
+		var err error
+		if weekday, err = parseWeekday(*day); err != nil {
+			return err
+		}
+	}
+
+	timetables, err := tf.load(client)
+	if err != nil {
+		return err
+	}
+
+	if weekday >= 0 {
+		timetables = filterByWeekday(timetables, weekday)
+	}
+
+	if *output != "" {
+		return exportFile(*output, *format, timetables)
+	}
+	return writeExport(stdout, *format, timetables)
+}
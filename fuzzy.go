@@ -0,0 +1,218 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// aliasSeparator joins the names of a show in FilterValue. The list hands
+// fuzzyFilter nothing but filter values, so every alias travels in one
+// string.
+const aliasSeparator = "\x1f"
+
+// substringBonus ranks contiguous matches above any scattered match.
+const substringBonus = 10_000
+
+// lowerRunes returns s as lower case runes, the form fuzzyMatch expects
+// its query in.
+func lowerRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// foldRune lower cases r, with a fast path for ASCII.
+func foldRune(r rune) rune {
+	if r < utf8.RuneSelf {
+		if 'A' <= r && r <= 'Z' {
+			r += 'a' - 'A'
+		}
+		return r
+	}
+	return unicode.ToLower(r)
+}
+
+func isWordBoundary(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r)
+}
+
+// fuzzyMatch matches the lower case query against text, ignoring case. Every
+// rune of query has to occur in text in order, otherwise ok is false. The
+// score of a match may be zero or negative, so only ok tells whether text
+// matched. Contiguous matches score above scattered ones, and matches near
+// the start or on word boundaries score higher. The rune indexes of text that
+// matched are appended to indexes[:0].
+func fuzzyMatch(query []rune, text string, indexes []int) (score int, _ []int, ok bool) {
+	indexes = indexes[:0]
+	if len(query) == 0 {
+		return 1, indexes, true
+	}
+
+	// Reject without allocating, most targets do not match
+	asciiQuery := true
+	for _, r := range query {
+		asciiQuery = asciiQuery && r < utf8.RuneSelf
+	}
+	q := 0
+	for i := 0; i < len(text) && q < len(query); {
+		c := text[i]
+		if c < utf8.RuneSelf {
+			if 'A' <= c && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			if rune(c) == query[q] {
+				q++
+			}
+			i++
+			continue
+		}
+		// Only K (U+212A) and İ (U+0130) lower case to ASCII, an ASCII
+		// query skips every other byte of native titles without decoding
+		if asciiQuery && c != 0xC4 && c != 0xE2 {
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if unicode.ToLower(r) == query[q] {
+			q++
+		}
+		i += size
+	}
+	if q < len(query) {
+		return 0, indexes, false
+	}
+
+	// Titles fit the buffer, so this stays on the stack
+	var buf [128]rune
+	runes := buf[:0]
+	for _, r := range text {
+		runes = append(runes, foldRune(r))
+	}
+
+	if start := indexRunes(runes, query); start >= 0 {
+		for i := range query {
+			indexes = append(indexes, start+i)
+		}
+		score = substringBonus - min(start, 100)
+		if start == 0 || isWordBoundary(runes[start-1]) {
+			score += 100
+		}
+		if len(query) == len(runes) {
+			score += 100
+		}
+		return score, indexes, true
+	}
+
+	for i, r := range runes {
+		if r != query[len(indexes)] {
+			continue
+		}
+		score += 10
+		if len(indexes) > 0 && indexes[len(indexes)-1] == i-1 {
+			score += 15
+		}
+		if i == 0 || isWordBoundary(runes[i-1]) {
+			score += 10
+		}
+		if indexes = append(indexes, i); len(indexes) == len(query) {
+			break
+		}
+	}
+	return score - min(indexes[0], 20), indexes, true
+}
+
+// indexRunes returns the index of the first occurrence of sub in runes, or
+// -1 if there is none.
+func indexRunes(runes, sub []rune) int {
+	for i := 0; i+len(sub) <= len(runes); i++ {
+		j := 0
+		for j < len(sub) && runes[i+j] == sub[j] {
+			j++
+		}
+		if j == len(sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// aliasMatcher matches a query against the aliases in filter values. It
+// reuses its buffers between targets, so the matched indexes it returns are
+// only valid until the next call.
+type aliasMatcher struct {
+	query   []rune
+	best    []int
+	scratch []int
+}
+
+func newAliasMatcher(term string) *aliasMatcher {
+	return &aliasMatcher{query: lowerRunes(term)}
+}
+
+// match returns the best score across the aliases in target, the index of
+// the alias that got it, or -1 if none matched, and the runes it matched in
+// that alias.
+func (a *aliasMatcher) match(target string) (score, alias int, indexes []int) {
+	alias = -1
+	a.best = a.best[:0]
+	for i := 0; ; i++ {
+		name, rest, more := strings.Cut(target, aliasSeparator)
+		if name != "" {
+			var s int
+			var ok bool
+			if s, a.scratch, ok = fuzzyMatch(a.query, name, a.scratch); ok && (alias < 0 || s > score) {
+				score, alias = s, i
+				a.best, a.scratch = a.scratch, a.best
+			}
+		}
+		if !more {
+			return score, alias, a.best
+		}
+		target = rest
+	}
+}
+
+// fuzzyFilter ranks the targets that match term, best first. Ties keep the
+// list order. Matches on the display title carry the matched runes of
+// animeItem.Title so the delegate can highlight them, matches on another
+// alias carry none.
+func fuzzyFilter(term string, targets []string) []list.Rank {
+	matcher := newAliasMatcher(term)
+
+	type match struct {
+		rank  list.Rank
+		score int
+	}
+	var matches []match
+
+	for i, target := range targets {
+		score, alias, indexes := matcher.match(target)
+		if alias < 0 {
+			continue
+		}
+
+		rank := list.Rank{Index: i}
+		if alias == 0 {
+			title, _, _ := strings.Cut(target, aliasSeparator)
+			rank.MatchedIndexes = wrappedIndexes(title, cfg.UI.TitleWidth, slices.Clone(indexes))
+		}
+		matches = append(matches, match{rank: rank, score: score})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	ranks := make([]list.Rank, len(matches))
+	for i, m := range matches {
+		ranks[i] = m.rank
+	}
+	return ranks
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		query   string
+		text    string
+		ok      bool
+		indexes []int
+	}{
+		{"fri", "Sousou no Frieren", true, []int{10, 11, 12}},
+		{"snf", "Sousou no Frieren", true, []int{0, 7, 10}},
+		{"frx", "Sousou no Frieren", false, nil},
+		{"フリ", "葬送のフリーレン", true, []int{3, 4}},
+		// The Kelvin sign lower cases to an ASCII k
+		{"kel", "\u212Aelvin", true, []int{0, 1, 2}},
+		// Late scattered matches score zero or less but still match
+		{"xz", "aaaaaaaaaaaaaaaaaaaaaxaaaz", true, []int{21, 25}},
+		{"xz", strings.Repeat("a", 60) + "x" + strings.Repeat("a", 30) + "z", true, []int{60, 91}},
+	}
+
+	for _, tt := range tests {
+		_, indexes, ok := fuzzyMatch(lowerRunes(tt.query), tt.text, nil)
+		if ok != tt.ok {
+			t.Errorf("fuzzyMatch(%q, %q) ok = %v, want %v", tt.query, tt.text, ok, tt.ok)
+			continue
+		}
+		if ok && !slices.Equal(indexes, tt.indexes) {
+			t.Errorf("fuzzyMatch(%q, %q) indexes = %v, want %v", tt.query, tt.text, indexes, tt.indexes)
+		}
+	}
+}
+
+func TestFuzzyFilterKeepsLateScatteredMatches(t *testing.T) {
+	targets := []string{
+		"Sousou no Frieren",
+		"aaaaaaaaaaaaaaaaaaaaaxaaaz",
+		"One Piece" + aliasSeparator + "aaaaaaaaaaaaaaaaaaaaaaaaaxaaz",
+	}
+
+	ranks := fuzzyFilter("xz", targets)
+	var got []int
+	for _, rank := range ranks {
+		got = append(got, rank.Index)
+	}
+	if want := []int{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("fuzzyFilter ranked %v, want %v", got, want)
+	}
+}
+
+func TestFuzzyFilterRanksContiguousFirst(t *testing.T) {
+	targets := []string{
+		"Frieren: Beyond Journey's End",
+		"Fire Force",
+		"Sousou no Frieren",
+	}
+
+	ranks := fuzzyFilter("fire", targets)
+	if len(ranks) != 3 || ranks[0].Index != 1 {
+		t.Fatalf("fuzzyFilter ranked %v, want Fire Force first", ranks)
+	}
+	if want := []int{0, 1, 2, 3}; !slices.Equal(ranks[0].MatchedIndexes, want) {
+		t.Errorf("matched indexes = %v, want %v", ranks[0].MatchedIndexes, want)
+	}
+}
+
+// benchmarkTitles returns n filter values shaped like the timetable's, a
+// title followed by its English, romaji and native names.
+func benchmarkTitles(n int) []string {
+	words := []string{
+		"Sousou", "no", "Frieren", "Kusuriya", "Hitorigoto", "Dungeon", "Meshi",
+		"Shingeki", "Kyojin", "Boku", "Hero", "Academia", "Jujutsu", "Kaisen",
+		"Tensei", "Shitara", "Slime", "Datta", "Ken", "Spy", "Family", "One",
+		"Piece", "Kimetsu", "Yaiba", "Oshi", "Ko", "Season", "Part", "Movie",
+	}
+	english := []string{"The", "Apothecary", "Diaries", "Delicious", "in", "Attack", "on", "Titan", "Demon", "Slayer", "Journey's", "End"}
+
+	targets := make([]string, n)
+	for i := range targets {
+		title := fmt.Sprintf("%s %s %s %d",
+			words[i%len(words)], words[(i*7+3)%len(words)], words[(i*13+5)%len(words)], i%4+1)
+		eng := fmt.Sprintf("%s %s %s", english[i%len(english)], english[(i*5+1)%len(english)], english[(i*11+2)%len(english)])
+		targets[i] = strings.Join([]string{title, eng, strings.ToLower(title), "葬送のフリーレン"}, aliasSeparator)
+	}
+	return targets
+}
+
+func BenchmarkFuzzyFilter(b *testing.B) {
+	targets := benchmarkTitles(3000)
+	for _, term := range []string{"f", "frieren", "atk tn", "zzz"} {
+		b.Run(term, func(b *testing.B) {
+			for b.Loop() {
+				fuzzyFilter(term, targets)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// minGridColumnWidth is the narrowest a day column gets, border
+	// included. Narrower terminals get fewer columns per row.
+	minGridColumnWidth = 26
+	// minGridRowHeight is the lowest a row of days gets, border included.
+	// Rows that do not fit are scrolled to keep the focused day visible.
+	minGridRowHeight = 8
+	// minGridDayWidth and minGridDayHeight fit a day's border and padding
+	// around one cell, and its header line. Terminals smaller than that crop
+	// the grid instead of breaking the boxes.
+	minGridDayWidth  = 5
+	minGridDayHeight = 3
+)
+
+// weekdays are the days of an ISO week, in order.
+var weekdays = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday,
+}
+
+var gridHeaderStyle = lipgloss.NewStyle().Bold(true)
+
+// gridView renders the week as bordered day columns that fill width and
+// height, wrapping onto more rows when seven columns do not fit.
+func (m weeklyModel) gridView(width, height int) string {
+	columns := min(max(width/minGridColumnWidth, 1), len(weekdays))
+	rows := (len(weekdays) + columns - 1) / columns
+
+	// Only show as many rows as fit, starting at the focused day's row
+	visibleRows := min(max(height/minGridRowHeight, 1), rows)
+	focusedRow := (int(m.focusedDay) + 6) % 7 / columns
+	firstRow := min(focusedRow, rows-visibleRows)
+
+	columnWidth := max(width/columns, minGridDayWidth)
+	rowHeight := max(height/visibleRows, minGridDayHeight)
+
+	var rowViews []string
+	for row := firstRow; row < firstRow+visibleRows; row++ {
+		var days []string
+		for _, day := range weekdays[row*columns : min((row+1)*columns, len(weekdays))] {
+			days = append(days, m.renderGridDay(day, columnWidth, rowHeight))
+		}
+		rowViews = append(rowViews, lipgloss.JoinHorizontal(lipgloss.Top, days...))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rowViews...)
+}
+
+// renderGridDay renders one day as a box of the given outer size, listing
+// as many episodes as fit.
+func (m weeklyModel) renderGridDay(day time.Weekday, width, height int) string {
+	style, headerStyle := dayStyle, gridHeaderStyle
+	if day == m.focusedDay {
+		style = focusedDayStyle
+		headerStyle = headerStyle.Foreground(lipgloss.Color(cfg.UI.FocusedDayBorder))
+	}
+	// Width and Height include the padding but not the border
+	style = style.Padding(0, 1).Width(width - 2).Height(height - 2).MaxHeight(height)
+	inner := width - 4
+
+	date := weekStart(m.year, m.week).AddDate(0, 0, (int(day)+6)%7)
+	lines := []string{headerStyle.Render(truncate(fmt.Sprintf("%s %s", day.String()[:3], date.Format("Jan 2")), inner))}
+
+	items := m.filterAnimeByDay(day)
+	if len(items) == 0 {
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(truncate("Nothing airs", inner)))
+	}
+
+	// One line goes to the header and one may go to "+N more"
+	room := height - 3
+	weekStart := weekStart(m.year, m.week)
+	now := time.Now()
+	for n, item := range items {
+		if n == room-1 && len(items) > room {
+			lines = append(lines, fmt.Sprintf("+%d more", len(items)-n))
+			break
+		}
+
+		anime := item.(animeItem)
+		listed, _ := m.delayMode.listedDate(anime.anime, weekStart)
+		line := listed.Format("15:04") + " "
+		if anime.watched {
+			line += "★ "
+		}
+		line = truncate(line+strings.TrimSpace(anime.anime.Title), inner)
+
+		switch {
+		case anime.anime.isDelayed():
+			line = lipgloss.NewStyle().Foreground(delayedColor).Render(line)
+		case anime.anime.isAiring(now):
+			line = lipgloss.NewStyle().Foreground(airingColor).Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	// Lines past the inner height would push the bottom border out
+	lines = lines[:min(len(lines), height-2)]
+	return style.Render(strings.Join(lines, "\n"))
+}
+
+// truncate shortens s to at most width cells, ending it with an ellipsis
+// when it was cut.
+func truncate(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && lipgloss.Width(string(runes))+1 > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
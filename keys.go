@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// forceQuitKey always quits, whatever the other keys are bound to, so it
+// cannot be bound to an action.
+const forceQuitKey = "ctrl+c"
+
+// keyAction is an action that can be bound in the [keys] section of the
+// config file, with its default keys.
+type keyAction struct {
+	name string
+	keys []string
+}
+
+// keyActions lists every action, in the order `baka config` prints them.
+// The names of the keys are those of tea.KeyMsg.String.
+var keyActions = []keyAction{
+	{"up", []string{"up", "k"}},
+	{"down", []string{"down", "j"}},
+	{"prev_page", []string{"pgup", "b", "u"}},
+	{"next_page", []string{"pgdown", "f", "d"}},
+	{"first", []string{"g", "home"}},
+	{"last", []string{"G", "end"}},
+	{"prev_day", []string{"left", "h"}},
+	{"next_day", []string{"right", "l"}},
+	{"today", []string{"t"}},
+	{"jump_day", []string{"1", "2", "3", "4", "5", "6", "7"}},
+	{"prev_week", []string{"["}},
+	{"next_week", []string{"]"}},
+	{"details", []string{"enter"}},
+	{"open_stream", []string{"o"}},
+	{"watch", []string{"w"}},
+	{"hide", []string{"x", "backspace"}},
+	{"filter", []string{"/"}},
+	{"search", []string{"s"}},
+	{"focus", []string{"tab", "shift+tab"}},
+	{"air_type", []string{"a"}},
+	{"my_shows", []string{"m"}},
+	{"hidden_shows", []string{"U"}},
+	{"delay_mode", []string{"D"}},
+	{"week_grid", []string{"v"}},
+	{"refresh", []string{"r"}},
+	{"export_view", []string{"e"}},
+	{"export_week", []string{"E"}},
+	{"export_format", []string{"F"}},
+	{"toggle_title", []string{"T"}},
+	{"toggle_status", []string{"S"}},
+	{"toggle_pagination", []string{"P"}},
+	{"toggle_help", []string{"H"}},
+	{"full_help", []string{"?"}},
+	{"back", []string{"esc"}},
+	{"quit", []string{"q"}},
+}
+
+var keyPresetNames = []string{"default", "vim", "emacs"}
+
+// keyPresets rebind some actions of keyActions. The default keys already
+// move like vim, the vim preset moves pages with ctrl as well.
+var keyPresets = map[string]map[string][]string{
+	"default": {},
+	"vim": {
+		"prev_page": {"pgup", "ctrl+b", "ctrl+u"},
+		"next_page": {"pgdown", "ctrl+f", "ctrl+d"},
+	},
+	"emacs": {
+		"up":        {"up", "ctrl+p"},
+		"down":      {"down", "ctrl+n"},
+		"prev_page": {"pgup", "alt+v"},
+		"next_page": {"pgdown", "ctrl+v"},
+		"first":     {"home", "alt+<"},
+		"last":      {"end", "alt+>"},
+		"prev_day":  {"left", "ctrl+b"},
+		"next_day":  {"right", "ctrl+f"},
+		"filter":    {"/", "ctrl+s"},
+		"back":      {"esc", "ctrl+g"},
+	},
+}
+
+// keys returns the keys bound to action, from the config file, the preset
+// or the defaults, in that order.
+func (k keysConfig) keys(action string) []string {
+	if keys, ok := k.Bindings[action]; ok {
+		return keys
+	}
+	if keys, ok := keyPresets[k.Preset][action]; ok {
+		return keys
+	}
+	i := slices.IndexFunc(keyActions, func(a keyAction) bool { return a.name == action })
+	return keyActions[i].keys
+}
+
+// check reports the first key that is bound to two actions. Every view
+// shares the keys, so one key always does the same thing.
+func (k keysConfig) check() error {
+	bound := map[string]string{forceQuitKey: "force quit"}
+	for _, action := range keyActions {
+		for _, name := range k.keys(action.name) {
+			if other, ok := bound[name]; ok && other != action.name {
+				return fmt.Errorf("keys: %q is bound to both %s and %s", name, other, action.name)
+			}
+			bound[name] = action.name
+		}
+	}
+	return nil
+}
+
+// newKeyBinding binds the configured keys of action, showing them in the
+// help next to description.
+func newKeyBinding(action, description string) key.Binding {
+	keys := cfg.Keys.keys(action)
+	return key.NewBinding(
+		key.WithKeys(keys...),
+		key.WithHelp(keyHelp(keys), description),
+	)
+}
+
+// keyHelp joins key names the way the help shows them, with arrows for the
+// arrow keys.
+func keyHelp(keys []string) string {
+	labels := make([]string, len(keys))
+	for i, name := range keys {
+		switch name {
+		case "up":
+			name = "↑"
+		case "down":
+			name = "↓"
+		case "left":
+			name = "←"
+		case "right":
+			name = "→"
+		case "pgdown":
+			name = "pgdn"
+		}
+		labels[i] = name
+	}
+	return strings.Join(labels, "/")
+}
+
+// keyRangeHelp labels keys as a range when they are consecutive
+// characters, like 1-7, and lists every key otherwise.
+func keyRangeHelp(keys []string) string {
+	for i := 1; i < len(keys); i++ {
+		if len(keys[i-1]) != 1 || len(keys[i]) != 1 || keys[i][0] != keys[i-1][0]+1 {
+			return keyHelp(keys)
+		}
+	}
+	if len(keys) < 3 {
+		return keyHelp(keys)
+	}
+	return keys[0] + "-" + keys[len(keys)-1]
+}
+
+// firstKeyHelp labels b with its first key only, for the one line hints
+// that have no room for every key.
+func firstKeyHelp(b key.Binding) string {
+	return keyHelp(b.Keys()[:1])
+}
+
+// setListKeys binds the keys of a list to the configured keys.
+func setListKeys(keys *list.KeyMap) {
+	keys.CursorUp = newKeyBinding("up", "up")
+	keys.CursorDown = newKeyBinding("down", "down")
+	keys.PrevPage = newKeyBinding("prev_page", "prev page")
+	keys.NextPage = newKeyBinding("next_page", "next page")
+	keys.GoToStart = newKeyBinding("first", "go to start")
+	keys.GoToEnd = newKeyBinding("last", "go to end")
+	keys.Filter = newKeyBinding("filter", "filter")
+	keys.ClearFilter = newKeyBinding("back", "clear filter")
+	keys.CancelWhileFiltering = newKeyBinding("back", "cancel")
+	keys.ShowFullHelp = newKeyBinding("full_help", "more")
+	keys.CloseFullHelp = newKeyBinding("full_help", "close help")
+	keys.Quit = newKeyBinding("quit", "quit")
+}
+
+// weeklyHelpKeys feeds the weekly view's footer with the bindings that
+// are active right now, so the help never lists keys that do nothing.
+type weeklyHelpKeys struct {
+	list     list.KeyMap
+	keys     *listKeyMap
+	delegate *delegateKeyMap
+	grid     bool
+}
+
+func (k weeklyHelpKeys) ShortHelp() []key.Binding {
+	if k.grid {
+		return []key.Binding{k.keys.prevDay, k.keys.nextDay, k.keys.toggleGrid, k.keys.today, k.keys.showFullHelp, k.list.Quit}
+	}
+	return []key.Binding{
+		k.list.CursorUp, k.list.CursorDown, k.keys.prevDay, k.keys.nextDay,
+		k.delegate.choose, k.list.Filter, k.keys.showFullHelp, k.list.Quit,
+	}
+}
+
+func (k weeklyHelpKeys) FullHelp() [][]key.Binding {
+	navigation := []key.Binding{
+		k.keys.prevDay, k.keys.nextDay, k.keys.today, k.keys.jumpDay,
+		k.keys.prevWeek, k.keys.nextWeek,
+	}
+	if !k.grid {
+		navigation = append([]key.Binding{k.list.CursorUp, k.list.CursorDown, k.list.GoToStart, k.list.GoToEnd}, navigation...)
+	}
+
+	columns := [][]key.Binding{navigation}
+	if !k.grid {
+		columns = append(columns, []key.Binding{
+			k.delegate.choose, k.delegate.open, k.delegate.watch, k.delegate.remove,
+			k.list.Filter, k.list.ClearFilter,
+		})
+	}
+	// The title, status and pagination bars belong to the list
+	toggles := []key.Binding{k.keys.toggleHelpMenu, k.keys.closeFullHelp, k.list.Quit}
+	if !k.grid {
+		toggles = append([]key.Binding{k.keys.toggleTitleBar, k.keys.toggleStatusBar, k.keys.togglePagination}, toggles...)
+	}
+	return append(columns,
+		[]key.Binding{
+			k.keys.cycleAirType, k.keys.toggleMyShows, k.keys.toggleHidden, k.keys.cycleDelayMode,
+			k.keys.toggleGrid, k.keys.search, k.keys.refresh, k.keys.exportView, k.keys.exportWeek, k.keys.cycleExportFormat,
+		},
+		toggles,
+	)
+}
+
+// detailKeyMap holds the keys of the detail view.
+type detailKeyMap struct {
+	open  key.Binding
+	close key.Binding
+	quit  key.Binding
+}
+
+// newDetailKeyMap closes the detail view with the details key that opened
+// it as well as with back.
+func newDetailKeyMap() *detailKeyMap {
+	closeKeys := slices.Concat(cfg.Keys.keys("back"), cfg.Keys.keys("details"))
+	return &detailKeyMap{
+		open: newKeyBinding("open_stream", "open stream"),
+		close: key.NewBinding(
+			key.WithKeys(closeKeys...),
+			key.WithHelp(keyHelp(closeKeys), "close"),
+		),
+		quit: newKeyBinding("quit", "quit"),
+	}
+}
+
+func (k *detailKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.open, k.close, k.quit}
+}
+
+func (k *detailKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
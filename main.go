@@ -2,20 +2,20 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"os"
-	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 	"unicode"
 
+	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -48,7 +48,8 @@ var (
 )
 
 type animeItem struct {
-	anime AnimeTimetable
+	anime   AnimeTimetable
+	watched bool
 }
 
 func (i animeItem) Title() string {
@@ -59,8 +60,11 @@ func (i animeItem) Title() string {
 		title = "Unknown Title"
 	}
 
-	maxWidth := 50
+	return wrapTitle(title, cfg.UI.TitleWidth)
+}
 
+// wrapTitle pads title to maxWidth and wraps longer titles onto more lines.
+func wrapTitle(title string, maxWidth int) string {
 	if len(title) <= maxWidth {
 		return fmt.Sprintf("%-*s", maxWidth, title)
 	}
@@ -91,120 +95,164 @@ func (i animeItem) Title() string {
 	return strings.Join(lines, "\n")
 }
 
-func (i animeItem) Description() string {
-	return fmt.Sprintf("Episode %d • %s • %s",
-		i.anime.EpisodeNumber,
-		i.anime.EpisodeDate.Format("Jan 2, 15:04"),
-		i.anime.AirType)
-}
-
-// Fuzzy search scoring function
-func fuzzyScore(query, text string) int {
-	query = strings.ToLower(query)
-	text = strings.ToLower(text)
-
-	if query == "" {
-		return 1000 // High score for empty query
+// wrappedIndexes translates rune indexes of title into the same runes of
+// wrapTitle(title, maxWidth), which drops the spaces it breaks lines at and
+// adds padding and newlines.
+func wrappedIndexes(title string, maxWidth int, indexes []int) []int {
+	if len(title) <= maxWidth || len(indexes) == 0 {
+		return indexes
 	}
 
-	if strings.Contains(text, query) {
-		// Exact substring match gets high score
-		return 100 + (100 - len(query))
-	}
-
-	// Calculate fuzzy match score
-	score := 0
-	queryRunes := []rune(query)
-	textRunes := []rune(text)
-
-	queryIndex := 0
-	for textIndex, textRune := range textRunes {
-		if queryIndex < len(queryRunes) && unicode.ToLower(textRune) == unicode.ToLower(queryRunes[queryIndex]) {
-			// Match found - give points based on position and consecutiveness
-			positionBonus := max(0, 50-textIndex)
-			score += 10 + positionBonus
-			queryIndex++
+	wrapped := []rune(wrapTitle(title, maxWidth))
+	mapped := make([]int, 0, len(indexes))
+	w := 0
+	for t, r := range []rune(title) {
+		// Runes missing from the wrapped title are spaces at a break
+		for w < len(wrapped) && wrapped[w] != r && !unicode.IsSpace(r) {
+			w++
 		}
+		if w == len(wrapped) {
+			break
+		}
+		if wrapped[w] != r {
+			continue
+		}
+		if len(mapped) < len(indexes) && indexes[len(mapped)] == t {
+			mapped = append(mapped, w)
+		}
+		w++
 	}
-
-	// Bonus for matching all characters
-	if queryIndex == len(queryRunes) {
-		score += 20
-	}
-
-	return score
+	return mapped
 }
 
-func max(a, b int) int {
-	if a > b {
-		return a
+func (i animeItem) Description() string {
+	watched := ""
+	if i.watched {
+		watched = "★ "
 	}
-	return b
+	description := fmt.Sprintf("%sEpisode %d • %s • %s",
+		watched,
+		i.anime.EpisodeNumber,
+		i.anime.EpisodeDate.Format("Jan 2, 15:04"),
+		i.anime.AirType)
+	// A delayed episode does not air at EpisodeDate, so it gets no countdown
+	if i.anime.isDelayed() {
+		description += " • " + i.anime.delayBadge()
+	} else {
+		description += " • " + i.anime.countdown(time.Now())
+	}
+	return description
 }
 
+// aliasLabels name the aliases in FilterValue, in order.
+var aliasLabels = []string{"Title", "English", "Romaji", "Native"}
+
 func (i animeItem) FilterValue() string {
-	// Use title for fuzzy matching
-	return i.anime.Title
+	return strings.Join([]string{strings.TrimSpace(i.anime.Title), i.anime.English, i.anime.Romaji, i.anime.Native}, aliasSeparator)
 }
 
-// Custom filter function for fuzzy search
-func fuzzyFilter(term string, targets []string) []list.Rank {
-	var ranks []list.Rank
+type listKeyMap struct {
+	toggleTitleBar    key.Binding
+	toggleStatusBar   key.Binding
+	togglePagination  key.Binding
+	toggleHelpMenu    key.Binding
+	prevWeek          key.Binding
+	nextWeek          key.Binding
+	cycleAirType      key.Binding
+	toggleMyShows     key.Binding
+	toggleHidden      key.Binding
+	exportView        key.Binding
+	exportWeek        key.Binding
+	cycleExportFormat key.Binding
+	cycleDelayMode    key.Binding
+	refresh           key.Binding
+	search            key.Binding
+	toggleGrid        key.Binding
+	today             key.Binding
+	jumpDay           key.Binding
+	prevDay           key.Binding
+	nextDay           key.Binding
+	showFullHelp      key.Binding
+	closeFullHelp     key.Binding
+	filter            key.Binding
+	focus             key.Binding
+	back              key.Binding
+	quit              key.Binding
+	// up and down move the stream picker. Unlike the list's own bindings,
+	// they stay enabled when the day behind the picker is empty
+	up   key.Binding
+	down key.Binding
+}
 
-	for i, target := range targets {
-		score := fuzzyScore(term, target)
-		if score > 0 {
-			ranks = append(ranks, list.Rank{
-				Index:          i,
-				MatchedIndexes: nil, // We'll let the list handle highlighting
-			})
-		}
-	}
+func newListKeyMap() *listKeyMap {
+	// The nth key jumps to the nth day of the week
+	days := cfg.Keys.keys("jump_day")
 
-	// Sort by score (higher scores first)
-	for i := 0; i < len(ranks)-1; i++ {
-		for j := i + 1; j < len(ranks); j++ {
-			scoreI := fuzzyScore(term, targets[ranks[i].Index])
-			scoreJ := fuzzyScore(term, targets[ranks[j].Index])
-			if scoreI < scoreJ {
-				ranks[i], ranks[j] = ranks[j], ranks[i]
-			}
-		}
+	return &listKeyMap{
+		toggleTitleBar:    newKeyBinding("toggle_title", "toggle title"),
+		toggleStatusBar:   newKeyBinding("toggle_status", "toggle status"),
+		togglePagination:  newKeyBinding("toggle_pagination", "toggle pagination"),
+		toggleHelpMenu:    newKeyBinding("toggle_help", "toggle help"),
+		prevWeek:          newKeyBinding("prev_week", "previous week"),
+		nextWeek:          newKeyBinding("next_week", "next week"),
+		cycleAirType:      newKeyBinding("air_type", "cycle air type"),
+		toggleMyShows:     newKeyBinding("my_shows", "my shows"),
+		toggleHidden:      newKeyBinding("hidden_shows", "hidden shows"),
+		exportView:        newKeyBinding("export_view", "export view"),
+		exportWeek:        newKeyBinding("export_week", "export week"),
+		cycleExportFormat: newKeyBinding("export_format", "export format"),
+		cycleDelayMode:    newKeyBinding("delay_mode", "cycle delay mode"),
+		refresh:           newKeyBinding("refresh", "refresh"),
+		search:            newKeyBinding("search", "search all anime"),
+		toggleGrid:        newKeyBinding("week_grid", "week grid"),
+		today:             newKeyBinding("today", "today"),
+		jumpDay: key.NewBinding(
+			key.WithKeys(days...),
+			key.WithHelp(keyRangeHelp(days), "mon-sun"),
+		),
+		prevDay:       newKeyBinding("prev_day", "previous day"),
+		nextDay:       newKeyBinding("next_day", "next day"),
+		showFullHelp:  newKeyBinding("full_help", "more"),
+		closeFullHelp: newKeyBinding("full_help", "close help"),
+		filter:        newKeyBinding("filter", "filter"),
+		focus:         newKeyBinding("focus", "switch focus"),
+		back:          newKeyBinding("back", "back"),
+		quit:          newKeyBinding("quit", "quit"),
+		up:            newKeyBinding("up", "up"),
+		down:          newKeyBinding("down", "down"),
 	}
+}
 
-	return ranks
+// setExportFormat shows the format files are exported as in the help of
+// the export keys.
+func (k *listKeyMap) setExportFormat(format string) {
+	k.exportView.SetHelp(k.exportView.Help().Key, "export view as "+format)
+	k.exportWeek.SetHelp(k.exportWeek.Help().Key, "export week as "+format)
 }
 
-type listKeyMap struct {
-	toggleTitleBar   key.Binding
-	toggleStatusBar  key.Binding
-	togglePagination key.Binding
-	toggleHelpMenu   key.Binding
+type fetchTimetableMsg struct {
+	query      TimetableQuery
+	timetables []AnimeTimetable
+	fetchedAt  time.Time
+	// stale is set when the timetable came from an expired cache
+	stale bool
 }
 
-func newListKeyMap() *listKeyMap {
-	return &listKeyMap{
-		toggleTitleBar: key.NewBinding(
-			key.WithKeys("T"),
-			key.WithHelp("T", "toggle title"),
-		),
-		toggleStatusBar: key.NewBinding(
-			key.WithKeys("S"),
-			key.WithHelp("S", "toggle status"),
-		),
-		togglePagination: key.NewBinding(
-			key.WithKeys("P"),
-			key.WithHelp("P", "toggle pagination"),
-		),
-		toggleHelpMenu: key.NewBinding(
-			key.WithKeys("H"),
-			key.WithHelp("H", "toggle help"),
-		),
-	}
+// refreshFailedMsg reports a failed background refresh of a stale timetable.
+type refreshFailedMsg struct {
+	query TimetableQuery
+	err   error
+}
+
+// errMsg reports that the timetable for query could not be loaded.
+type errMsg struct {
+	query TimetableQuery
+	err   error
 }
 
-type fetchTimetableMsg []AnimeTimetable
-type errMsg error
+// routesChangedMsg is sent by the item delegate after a route was hidden or
+// unhidden, so the list can be rebuilt without it.
+type routesChangedMsg struct{}
 
 type appState int
 
@@ -215,47 +263,100 @@ const (
 
 type weeklyModel struct {
 	state        appState
+	client       *APIClient
 	spinner      spinner.Model
 	allAnime     []animeItem
 	list         list.Model
 	focusedDay   time.Weekday
+	year         int
+	week         int
+	airType      string
+	watchlist    *routeSet
+	myShows      bool
+	hidden       *routeSet
+	showHidden   bool
+	delayMode    delayMode
+	exportFormat string
+	detail       *animeItem
+	detailAnime  *Anime
+	detailErr    error
+	picker       *streamPicker
+	search       *searchModel
+	grid         bool
+	open         opener
 	keys         *listKeyMap
 	delegateKeys *delegateKeyMap
-	err          error
-	width        int
-	height       int
+	detailKeys   *detailKeyMap
+	help         help.Model
+	showHelp     bool
+	// The list is rebuilt on every day and timetable change, so what the
+	// toggles hide is kept here
+	showTitle      bool
+	showStatusBar  bool
+	showPagination bool
+	err            error
+	fetchedAt      time.Time
+	refreshing     bool
+	offlineErr     error
+	width          int
+	height         int
 }
 
-func initialModel(apiToken string) weeklyModel {
+func initialModel(client *APIClient, watchlist, hidden *routeSet) weeklyModel {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
 
-	// Set focused day to current day
-	currentDay := time.Now().Weekday()
+	// Set focused day to current day and week
+	now := time.Now()
+	currentDay := now.Weekday()
+	year, week := now.ISOWeek()
 
+	keys := newListKeyMap()
+	keys.setExportFormat(cfg.UI.ExportFormat)
 	delegateKeys := newDelegateKeyMap()
 
 	return weeklyModel{
-		state:      stateLoading,
-		spinner:    s,
-		allAnime:   []animeItem{},
-		list:       list.New([]list.Item{}, newItemDelegate(delegateKeys), 80, 24),
-		focusedDay: currentDay,
-		width:      80,
-		height:     24,
+		state:          stateLoading,
+		client:         client,
+		spinner:        s,
+		allAnime:       []animeItem{},
+		list:           newAnimeList([]list.Item{}, delegateKeys, watchlist, hidden, 80, 24),
+		focusedDay:     currentDay,
+		year:           year,
+		week:           week,
+		airType:        cfg.API.AirType,
+		watchlist:      watchlist,
+		hidden:         hidden,
+		exportFormat:   cfg.UI.ExportFormat,
+		open:           defaultOpener(),
+		keys:           keys,
+		delegateKeys:   delegateKeys,
+		detailKeys:     newDetailKeyMap(),
+		help:           help.New(),
+		showHelp:       true,
+		showTitle:      true,
+		showPagination: true,
+		width:          80,
+		height:         24,
 	}
 }
 
 func (m weeklyModel) Init() tea.Cmd {
 	return tea.Batch(
 		m.spinner.Tick,
-		fetchTimetableCmd,
+		minuteTick(),
+		fetchTimetableCmd(m.client, m.query()),
 		tea.EnterAltScreen,
 	)
 }
 
 func getSystemTimezone() string {
+	// An explicitly configured timezone wins over detection
+	if cfg.API.Timezone != "" {
+		return cfg.API.Timezone
+	}
+
 	// Method 1: Try to get timezone from environment variable
 	if timezone := os.Getenv("TZ"); timezone != "" {
 		return timezone
@@ -276,94 +377,110 @@ func getSystemTimezone() string {
 		return strings.TrimSpace(string(data))
 	}
 
-	return "Asia/Kolkata" // Fallback to a default timezone
+	return cfg.API.FallbackTimezone // Fallback to the configured default timezone
 }
 
-func getCacheFilePath() string {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "anime_cache.json" // fallback to current directory
-	}
-	return filepath.Join(homeDir, ".cache", "baka", "anime_schedule.json")
+func isCacheFresh(fetchedAt time.Time) bool {
+	// Cache is fresh while it's younger than the configured TTL
+	return time.Since(fetchedAt) < cfg.Cache.TTL
 }
 
-func saveTimetableCache(timetables []AnimeTimetable) error {
-	cacheFile := getCacheFilePath()
+// fetchTimetableCmd loads the timetable for query. Any cached copy is served
+// right away, and marked stale when it has expired so the model refreshes it
+// in the background.
+func fetchTimetableCmd(client *APIClient, query TimetableQuery) tea.Cmd {
+	return func() tea.Msg {
+		if err := query.Validate(); err != nil {
+			return errMsg{query: query, err: err}
+		}
 
-	// Create cache directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err != nil {
-		return err
-	}
+		if cached, err := loadTimetableCache(timetableCacheKey(query)); err == nil {
+			return fetchTimetableMsg{
+				query:      query,
+				timetables: cached.Timetables,
+				fetchedAt:  cached.FetchedAt,
+				stale:      !isCacheFresh(cached.FetchedAt),
+			}
+		}
 
-	data, err := json.MarshalIndent(timetables, "", "  ")
-	if err != nil {
-		return err
+		timetable, err := fetchAndCacheTimetable(client, query)
+		if err != nil {
+			return errMsg{query: query, err: err}
+		}
+		return fetchTimetableMsg{query: query, timetables: timetable, fetchedAt: time.Now()}
 	}
-
-	return os.WriteFile(cacheFile, data, 0644)
 }
 
-func loadTimetableCache() ([]AnimeTimetable, error) {
-	cacheFile := getCacheFilePath()
-
-	data, err := os.ReadFile(cacheFile)
-	if err != nil {
-		return nil, err
+// refreshTimetableCmd fetches the timetable from the API, bypassing the cache.
+func refreshTimetableCmd(client *APIClient, query TimetableQuery) tea.Cmd {
+	return func() tea.Msg {
+		timetable, err := fetchAndCacheTimetable(client, query)
+		if err != nil {
+			return refreshFailedMsg{query: query, err: err}
+		}
+		return fetchTimetableMsg{query: query, timetables: timetable, fetchedAt: time.Now()}
 	}
+}
 
-	var timetables []AnimeTimetable
-	if err := json.Unmarshal(data, &timetables); err != nil {
+// loadTimetable returns the timetable from a fresh cache or the API. When the
+// API cannot be reached it falls back to a stale cache, with a warning.
+func loadTimetable(client *APIClient, query TimetableQuery) ([]AnimeTimetable, error) {
+	if err := query.Validate(); err != nil {
 		return nil, err
 	}
 
-	return timetables, nil
-}
-
-func isCacheValid() bool {
-	cacheFile := getCacheFilePath()
+	cached, cacheErr := loadTimetableCache(timetableCacheKey(query))
+	if cacheErr == nil && isCacheFresh(cached.FetchedAt) {
+		return cached.Timetables, nil
+	}
 
-	info, err := os.Stat(cacheFile)
+	timetable, err := fetchAndCacheTimetable(client, query)
 	if err != nil {
-		return false
+		if cacheErr == nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s\nUsing timetable cached at %s\n", friendlyError(err), cached.FetchedAt.Format("Jan 2 15:04"))
+			return cached.Timetables, nil
+		}
+		return nil, err
 	}
-
-	// Cache is valid if it's less than 1 hour old
-	return time.Since(info.ModTime()) < time.Hour
+	return timetable, nil
 }
 
-func fetchTimetableCmd() tea.Msg {
-	// Try to load from cache first
-	if isCacheValid() {
-		if cachedTimetables, err := loadTimetableCache(); err == nil {
-			return fetchTimetableMsg(cachedTimetables)
-		}
+// fetchAndCacheTimetable fetches the timetable from the API and caches it.
+// A cached copy is revalidated with its ETag instead of downloaded again.
+func fetchAndCacheTimetable(client *APIClient, query TimetableQuery) ([]AnimeTimetable, error) {
+	key := timetableCacheKey(query)
+	previous, _ := loadTimetableCache(key)
+	etag := ""
+	if previous != nil {
+		etag = previous.ETag
 	}
 
-	apiToken, success := getEnvVariable("ANIMESCHEDULE_TOKEN")
-	if !success {
-		return errMsg(fmt.Errorf("ANIMESCHEDULE_TOKEN environment variable not set"))
+	res, err := client.FetchTimetables(query, etag)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get system timezone in proper format
-	timezone := getSystemTimezone()
-
-	options := map[string]any{
-		"airType": "sub",
-		"tz":      timezone,
+	entry := &timetableCacheEntry{
+		Key:        key,
+		URL:        res.URL,
+		ETag:       res.ETag,
+		FetchedAt:  time.Now(),
+		Timetables: res.Timetables,
 	}
-
-	timetable, err := fetchTimetables(apiToken, options)
-	if err != nil {
-		return errMsg(err)
+	if res.NotModified {
+		entry.Timetables = previous.Timetables
+		if entry.ETag == "" {
+			entry.ETag = previous.ETag
+		}
 	}
 
 	// Save to cache
-	if err := saveTimetableCache(timetable); err != nil {
+	if err := saveTimetableCache(entry); err != nil {
 		// Don't fail the whole operation if cache save fails
-		fmt.Printf("Warning: Failed to save cache: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Warning: Failed to save cache: %v\n", err)
 	}
 
-	return fetchTimetableMsg(timetable)
+	return entry.Timetables, nil
 }
 
 func getEnvVariable(key string) (string, bool) {
@@ -397,60 +514,201 @@ func getEnvVariable(key string) (string, bool) {
 func (m weeklyModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "q", "ctrl+c":
+		if msg.String() == forceQuitKey {
 			return m, tea.Quit
-		case "esc":
-			// Only quit if not in filter mode
-			if m.state == stateWeekly && m.list.FilterState() != list.Filtering {
+		}
+
+		if m.picker != nil {
+			return m.updateStreamPicker(msg)
+		}
+
+		// The detail view is modal, it only closes, quits or opens streams
+		if m.detail != nil {
+			switch {
+			case key.Matches(msg, m.detailKeys.quit):
 				return m, tea.Quit
+			case key.Matches(msg, m.detailKeys.close):
+				m.detail = nil
+				m.detailAnime = nil
+				m.detailErr = nil
+			case key.Matches(msg, m.detailKeys.open):
+				item := *m.detail
+				return m, func() tea.Msg { return showStreamsMsg{item: item} }
+			}
+			return m, nil
+		}
+
+		if m.search != nil {
+			return m.updateSearch(msg)
+		}
+
+		switch {
+		case key.Matches(msg, m.keys.quit):
+			// Quit keys may be letters, which type into the filter
+			if m.list.FilterState() != list.Filtering {
+				return m, tea.Quit
+			}
+		case key.Matches(msg, m.keys.back):
+			// Back clears the filter first, the list handles that
+			if m.state == stateWeekly && m.list.FilterState() == list.Unfiltered {
+				return m, tea.Quit
+			}
+		}
+
+		// Week navigation refetches, so it is also available from the
+		// loading and error screens
+		if m.list.FilterState() != list.Filtering {
+			switch {
+			case key.Matches(msg, m.keys.prevWeek):
+				return m.changeWeek(-1)
+			case key.Matches(msg, m.keys.nextWeek):
+				return m.changeWeek(1)
+			case key.Matches(msg, m.keys.cycleAirType):
+				m.airType = nextAirType(m.airType)
+				return m.refetch()
+			case key.Matches(msg, m.keys.today):
+				now := time.Now()
+				m.focusedDay = now.Weekday()
+				if year, week := now.ISOWeek(); year != m.year || week != m.week {
+					m.year, m.week = year, week
+					return m.refetch()
+				}
+				if m.state == stateWeekly {
+					m = m.updateListForDay()
+				}
+				return m, nil
 			}
 		}
 
 	case fetchTimetableMsg:
-		// Data loaded successfully, switch to weekly view
-		m.state = stateWeekly
-		m.keys = newListKeyMap()
-		m.delegateKeys = newDelegateKeyMap()
+		// Ignore responses for a timetable we already navigated away from
+		if msg.query != m.query() {
+			return m, nil
+		}
+
+		m.fetchedAt = msg.fetchedAt
+		m.refreshing = msg.stale
+		m.offlineErr = nil
+		var refresh tea.Cmd
+		if msg.stale {
+			refresh = refreshTimetableCmd(m.client, msg.query)
+		}
 
 		// Populate allAnime slice with anime
-		for _, anime := range msg {
+		m.allAnime = nil
+		for _, anime := range msg.timetables {
 			m.allAnime = append(m.allAnime, animeItem{anime: anime})
 		}
 
+		// Fresh data replacing the stale copy on screen keeps the list as is
+		if m.state == stateWeekly {
+			m = m.updateListBasedOnFilterState()
+			return m, refresh
+		}
+
+		// Data loaded successfully, switch to weekly view
+		m.state = stateWeekly
+
 		// Initialize the list
-		m.list = list.New([]list.Item{}, newItemDelegate(m.delegateKeys), m.width-4, m.height-6)
-		// Format initial day name with consistent width
-		dayName := fmt.Sprintf("%-9s", m.focusedDay.String())
-		m.list.Title = dayName
-		m.list.Styles.Title = titleStyle
-		m.list.SetShowHelp(false)
-		m.list.SetShowStatusBar(false)
+		m.list = newAnimeList([]list.Item{}, m.delegateKeys, m.watchlist, m.hidden, m.width-4, m.height-6)
+		m.list.Title = m.listTitle()
 
 		// Set filter input width to match anime list width
 		m.list.Styles.FilterPrompt = lipgloss.NewStyle().
-			Width(50).
-			MaxWidth(50).
+			Width(cfg.UI.TitleWidth).
+			MaxWidth(cfg.UI.TitleWidth).
 			Inline(true)
 		m.list.Styles.FilterCursor = lipgloss.NewStyle().
-			Width(50).
-			MaxWidth(50).
+			Width(cfg.UI.TitleWidth).
+			MaxWidth(cfg.UI.TitleWidth).
 			Inline(true)
 
 		m = m.updateListForDay()
+		return m, refresh
+
+	case refreshFailedMsg:
+		if msg.query == m.query() {
+			m.refreshing = false
+			m.offlineErr = msg.err
+		}
 		return m, nil
 
 	case errMsg:
-		m.err = msg
+		// Ignore errors for a timetable we already navigated away from
+		if msg.query == m.query() {
+			m.err = msg.err
+		}
+		return m, nil
+
+	case showDetailMsg:
+		m.detail = &msg.item
+		m.detailAnime = nil
+		m.detailErr = nil
+		return m, fetchAnimeCmd(m.client, msg.item.anime.Route)
+
+	case animeDetailMsg:
+		// Drop records for a detail view that was closed or replaced
+		if m.detail == nil || m.detail.anime.Route != msg.route {
+			return m, nil
+		}
+		m.detailAnime = msg.anime
+		m.detailErr = msg.err
+		return m, nil
+
+	case showStreamsMsg:
+		links := msg.item.anime.Streams.links()
+		if len(links) == 0 {
+			return m, m.list.NewStatusMessage(statusMessageStyle("No streams listed for " + msg.item.anime.Title))
+		}
+		m.picker = &streamPicker{title: msg.item.anime.Title, links: links}
 		return m, nil
 
+	case streamOpenedMsg:
+		if msg.err != nil {
+			return m, m.list.NewStatusMessage(statusMessageStyle("Failed to open " + msg.link.provider + ": " + msg.err.Error()))
+		}
+		return m, m.list.NewStatusMessage(statusMessageStyle("Opened " + msg.link.provider))
+
+	case routesChangedMsg:
+		m = m.updateListBasedOnFilterState()
+		return m, nil
+
+	case minuteTickMsg:
+		// Descriptions read the clock, redrawing updates the countdowns
+		return m, minuteTick()
+
+	case searchDebounceMsg:
+		if m.search == nil || msg.seq != m.search.seq {
+			return m, nil
+		}
+		return m, m.search.search(m.client)
+
+	case searchResultsMsg:
+		// Only the latest search counts, earlier ones may arrive late
+		if m.search == nil || msg.text != m.search.text {
+			return m, nil
+		}
+		m.search.searching = false
+		m.search.err = msg.err
+		if msg.err != nil {
+			// Enter on the same text retries a failed search
+			m.search.text = ""
+		}
+		cmd := m.search.results.SetItems(m.searchResultItems(msg.results))
+		m.search.results.ResetSelected()
+		return m, cmd
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		m.help.Width = msg.Width
 		if m.state == stateWeekly {
 			// Update the list model to use full terminal size
 			m.list.SetSize(msg.Width-4, msg.Height-6) // Account for title and help text
 		}
+		if m.search != nil {
+			m.search.setSize(msg.Width, msg.Height)
+		}
 		return m, nil
 	}
 
@@ -463,26 +721,100 @@ func (m weeklyModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case stateWeekly:
 		// Handle navigation between days first
 		if msg, ok := msg.(tea.KeyMsg); ok {
-			switch msg.String() {
-			case "left", "h":
+			switch {
+			case key.Matches(msg, m.keys.prevDay):
 				// Don't navigate if filtering is active
 				if m.list.FilterState() != list.Filtering {
 					m.focusedDay = m.getPreviousDay()
 					m = m.updateListForDay()
 					return m, nil
 				}
-			case "right", "l":
+			case key.Matches(msg, m.keys.nextDay):
 				// Don't navigate if filtering is active
 				if m.list.FilterState() != list.Filtering {
 					m.focusedDay = m.getNextDay()
 					m = m.updateListForDay()
 					return m, nil
 				}
-			case "/":
+			case key.Matches(msg, m.keys.filter):
+				// Filtering happens in the list, so leave the grid
+				m.grid = false
 				// When starting to filter, load all anime
 				m = m.loadAllAnimeForFiltering()
 				// Let the list handle the filter key
 			}
+
+			// Mode toggles are plain letters, so ignore them while typing a filter
+			if m.list.FilterState() != list.Filtering {
+				switch {
+				case key.Matches(msg, m.keys.toggleMyShows):
+					m.myShows = !m.myShows
+					m = m.updateListForDay()
+					return m, nil
+				case key.Matches(msg, m.keys.toggleHidden):
+					m.showHidden = !m.showHidden
+					m = m.updateListForDay()
+					return m, nil
+				case key.Matches(msg, m.keys.refresh):
+					m.refreshing = true
+					return m, refreshTimetableCmd(m.client, m.query())
+				case key.Matches(msg, m.keys.search):
+					m.search = newSearchModel(m.keys, m.delegateKeys, m.watchlist, m.width, m.height)
+					return m, textinput.Blink
+				case key.Matches(msg, m.keys.cycleDelayMode):
+					m.delayMode = m.delayMode.next()
+					m = m.updateListForDay()
+					return m, nil
+				case key.Matches(msg, m.keys.exportView):
+					suffix := strings.ToLower(m.focusedDay.String()[:3])
+					if m.list.FilterValue() != "" {
+						suffix = "filtered"
+					}
+					cmd := m.exportItems(m.list.VisibleItems(), suffix)
+					return m, cmd
+				case key.Matches(msg, m.keys.exportWeek):
+					cmd := m.exportItems(m.allAnimeItems(), "")
+					return m, cmd
+				case key.Matches(msg, m.keys.cycleExportFormat):
+					i := slices.Index(exportFormats, m.exportFormat)
+					m.exportFormat = exportFormats[(i+1)%len(exportFormats)]
+					m.keys.setExportFormat(m.exportFormat)
+					return m, m.list.NewStatusMessage(statusMessageStyle("Exports are written as " + m.exportFormat))
+				case key.Matches(msg, m.keys.jumpDay):
+					m.focusedDay = weekdays[slices.Index(m.keys.jumpDay.Keys(), msg.String())]
+					m = m.updateListForDay()
+					return m, nil
+				case key.Matches(msg, m.keys.toggleGrid):
+					m.grid = !m.grid
+					return m, nil
+				case key.Matches(msg, m.keys.toggleTitleBar):
+					m.showTitle = !m.showTitle
+					m.list.SetShowTitle(m.showTitle)
+					return m, nil
+				case key.Matches(msg, m.keys.toggleStatusBar):
+					m.showStatusBar = !m.showStatusBar
+					m.list.SetShowStatusBar(m.showStatusBar)
+					return m, nil
+				case key.Matches(msg, m.keys.togglePagination):
+					m.showPagination = !m.showPagination
+					m.list.SetShowPagination(m.showPagination)
+					return m, nil
+				case key.Matches(msg, m.keys.toggleHelpMenu):
+					m.showHelp = !m.showHelp
+					return m, nil
+				case key.Matches(msg, m.keys.showFullHelp):
+					m.help.ShowAll = !m.help.ShowAll
+					return m, nil
+				}
+			}
+
+			// The grid has no selection, enter opens the focused day's list
+			if m.grid {
+				if key.Matches(msg, m.delegateKeys.choose) {
+					m.grid = false
+				}
+				return m, nil
+			}
 		}
 
 		// Update the list model
@@ -507,14 +839,17 @@ func (m weeklyModel) View() string {
 	switch m.state {
 	case stateLoading:
 		if m.err != nil {
-			errorText := fmt.Sprintf("Error: %v\n\nPress q to quit", m.err)
+			errorText := fmt.Sprintf("Error: %s\n\nPress %s or %s to change week • %s to change air type • %s to quit",
+				friendlyError(m.err), firstKeyHelp(m.keys.prevWeek), firstKeyHelp(m.keys.nextWeek),
+				firstKeyHelp(m.keys.cycleAirType), firstKeyHelp(m.keys.quit))
 			return lipgloss.NewStyle().
 				Align(lipgloss.Center, lipgloss.Center).
 				Width(m.width).
 				Height(m.height).
 				Render(errorText)
 		}
-		loadingText := fmt.Sprintf("%s Fetching anime timetable...\n\nPress q to quit", m.spinner.View())
+		loadingText := fmt.Sprintf("%s Fetching %s anime timetable for %s...\n\nPress %s to quit",
+			m.spinner.View(), m.airType, m.weekRange(), firstKeyHelp(m.keys.quit))
 		return lipgloss.NewStyle().
 			Align(lipgloss.Center, lipgloss.Center).
 			Width(m.width).
@@ -522,19 +857,58 @@ func (m weeklyModel) View() string {
 			Render(loadingText)
 
 	case stateWeekly:
-		// Center the list view
-		centeredList := lipgloss.NewStyle().
-			Align(lipgloss.Center).
-			Width(m.width).
-			Render(m.list.View())
+		if m.picker != nil {
+			return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center,
+				renderStreamPicker(m.picker.title, m.picker.links, m.picker.cursor, m.streamPickerHint()))
+		}
 
-		helpText := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("241")).
-			Align(lipgloss.Center).
-			Width(m.width).
-			Render("← → / h l: navigate days • ↑↓: select anime • enter: choose • x: delete • q: quit")
+		centered := lipgloss.NewStyle().Align(lipgloss.Center).Width(m.width)
+
+		if m.detail != nil {
+			return lipgloss.Place(m.width, m.height-1, lipgloss.Center, lipgloss.Center, renderDetail(m.detail.anime, m.detailAnime, m.detailErr, m.width)) +
+				"\n" + centered.Render(m.help.ShortHelpView(m.detailKeys.ShortHelp()))
+		}
 
-		return centeredList + "\n" + helpText
+		if m.search != nil {
+			return m.search.View(m.width)
+		}
+
+		// The full help is taller than the short one and the cache status
+		// may wrap, the list or grid shrinks to make room for both
+		footer := ""
+		extra := 0
+		if m.showHelp {
+			footer = centered.Render(m.help.View(weeklyHelpKeys{
+				list:     m.list.KeyMap,
+				keys:     m.keys,
+				delegate: m.delegateKeys,
+				grid:     m.grid,
+			}))
+			extra = lipgloss.Height(footer) - 1
+		}
+		status := m.cacheStatus()
+		if status != "" {
+			status = centered.Foreground(delayedColor).Render(status)
+			extra += lipgloss.Height(status)
+		}
+
+		var centeredList string
+		if m.grid {
+			centeredList = titleStyle.Render(m.allDaysTitle()) + "\n\n" + m.gridView(m.width, m.height-5-extra)
+		} else {
+			l := m.list
+			l.SetSize(m.width-4, m.height-6-extra)
+			centeredList = centered.Render(l.View())
+		}
+
+		if status != "" {
+			centeredList += "\n" + status
+		}
+		if footer != "" {
+			centeredList += "\n" + footer
+		}
+
+		return centeredList
 	}
 
 	return ""
@@ -542,10 +916,35 @@ func (m weeklyModel) View() string {
 
 func (m weeklyModel) filterAnimeByDay(day time.Weekday) []list.Item {
 	var items []list.Item
+	weekStart := weekStart(m.year, m.week)
+	for _, item := range m.allAnimeItems() {
+		date, _ := m.delayMode.listedDate(item.(animeItem).anime, weekStart)
+		if date.Weekday() == day {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// allAnimeItems returns the week's anime as list items, limited to the
+// watchlist when "My shows" is on. Hidden shows are left out, except in the
+// hidden view which lists nothing else, and so are delayed episodes the
+// delay mode does not list this week.
+func (m weeklyModel) allAnimeItems() []list.Item {
+	var items []list.Item
+	weekStart := weekStart(m.year, m.week)
 	for _, anime := range m.allAnime {
-		if anime.anime.EpisodeDate.Weekday() == day {
-			items = append(items, anime)
+		if _, ok := m.delayMode.listedDate(anime.anime, weekStart); !ok {
+			continue
+		}
+		anime.watched = m.watchlist.has(anime.anime.Route)
+		if m.myShows && !anime.watched {
+			continue
+		}
+		if m.hidden.has(anime.anime.Route) != m.showHidden {
+			continue
 		}
+		items = append(items, anime)
 	}
 	return items
 }
@@ -556,9 +955,7 @@ func (m weeklyModel) updateListForDay() weeklyModel {
 	// If filtering is active, show all anime across all days
 	if m.list.FilterState() == list.Filtering || m.list.FilterValue() != "" {
 		// Show all anime when searching
-		for _, anime := range m.allAnime {
-			items = append(items, anime)
-		}
+		items = m.allAnimeItems()
 	} else {
 		// Show only current day's anime when not searching
 		items = m.filterAnimeByDay(m.focusedDay)
@@ -568,8 +965,11 @@ func (m weeklyModel) updateListForDay() weeklyModel {
 	currentFilter := m.list.FilterValue()
 	isFiltering := m.list.FilterState() == list.Filtering
 
-	// Recreate the list with new delegate
-	m.list = list.New(items, newItemDelegate(m.delegateKeys), m.width-4, m.height-6)
+	// Recreate the list with new delegate, keeping what the toggles hid
+	m.list = newAnimeList(items, m.delegateKeys, m.watchlist, m.hidden, m.width-4, m.height-6)
+	m.list.SetShowTitle(m.showTitle)
+	m.list.SetShowStatusBar(m.showStatusBar)
+	m.list.SetShowPagination(m.showPagination)
 
 	// Restore filter state if it was active
 	if currentFilter != "" {
@@ -581,15 +981,7 @@ func (m weeklyModel) updateListForDay() weeklyModel {
 		}
 	}
 
-	// Format day name with consistent width
-	dayName := fmt.Sprintf("%-9s", m.focusedDay.String())
-	if m.list.FilterState() == list.Filtering || m.list.FilterValue() != "" {
-		dayName = "All Days"
-	}
-	m.list.Title = dayName
-	m.list.Styles.Title = titleStyle
-	m.list.SetShowHelp(false)
-	m.list.SetShowStatusBar(false)
+	m.list.Title = m.listTitle()
 
 	return m
 }
@@ -620,16 +1012,108 @@ func (m weeklyModel) getNextDay() time.Weekday {
 	return time.Monday
 }
 
+// weekStart returns midnight on the Monday that starts the given ISO week.
+func weekStart(year, week int) time.Time {
+	// January 4th always falls in ISO week 1
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.Local)
+	offset := (int(jan4.Weekday()) + 6) % 7
+	return jan4.AddDate(0, 0, (week-1)*7-offset)
+}
+
+// weekRange formats the Monday to Sunday span of the focused week.
+func (m weeklyModel) weekRange() string {
+	start := weekStart(m.year, m.week)
+	end := start.AddDate(0, 0, 6)
+	return fmt.Sprintf("%s – %s", start.Format("Jan 2"), end.Format("Jan 2"))
+}
+
+func (m weeklyModel) listTitle() string {
+	if m.list.FilterState() == list.Filtering || m.list.FilterValue() != "" {
+		return m.allDaysTitle()
+	}
+	// Format day name with consistent width
+	return fmt.Sprintf("%-9s • %s • %s%s", m.focusedDay.String(), m.weekRange(), m.airType, m.modeSuffix())
+}
+
+func (m weeklyModel) allDaysTitle() string {
+	return fmt.Sprintf("All Days • %s • %s%s", m.weekRange(), m.airType, m.modeSuffix())
+}
+
+func (m weeklyModel) modeSuffix() string {
+	suffix := ""
+	if m.myShows {
+		suffix += " • My shows"
+	}
+	if m.showHidden {
+		suffix += " • Hidden"
+	}
+	if m.delayMode != delayShow {
+		suffix += " • " + m.delayMode.String()
+	}
+	return suffix
+}
+
+// cacheStatus describes how current the timetable on screen is, or returns
+// "" when it is fresh.
+func (m weeklyModel) cacheStatus() string {
+	switch {
+	case m.refreshing && isCacheFresh(m.fetchedAt):
+		return "Refreshing…"
+	case m.refreshing:
+		return fmt.Sprintf("Stale since %s • refreshing…", m.fetchedAt.Add(cfg.Cache.TTL).Format("Jan 2 15:04"))
+	case m.offlineErr != nil:
+		return fmt.Sprintf("Offline • showing timetable from %s • %s: retry (%s)",
+			m.fetchedAt.Format("Jan 2 15:04"), firstKeyHelp(m.keys.refresh), friendlyError(m.offlineErr))
+	}
+	return ""
+}
+
+// query is the timetable query for the focused week and air type.
+func (m weeklyModel) query() TimetableQuery {
+	return timetableQuery(m.year, m.week, m.airType)
+}
+
+// changeWeek moves the focused week by delta weeks and fetches its timetable.
+func (m weeklyModel) changeWeek(delta int) (tea.Model, tea.Cmd) {
+	m.year, m.week = weekStart(m.year, m.week).AddDate(0, 0, 7*delta).ISOWeek()
+	return m.refetch()
+}
+
+// refetch returns to the loading screen and fetches the timetable for the
+// current week and air type.
+func (m weeklyModel) refetch() (tea.Model, tea.Cmd) {
+	m.state = stateLoading
+	m.err = nil
+	return m, tea.Batch(m.spinner.Tick, fetchTimetableCmd(m.client, m.query()))
+}
+
+// exportItems writes items to a file in the working directory named after
+// the week and the optional suffix, and reports the outcome in the status bar.
+func (m *weeklyModel) exportItems(items []list.Item, suffix string) tea.Cmd {
+	var timetables []AnimeTimetable
+	for _, item := range items {
+		timetables = append(timetables, item.(animeItem).anime)
+	}
+
+	name := fmt.Sprintf("baka-%s-%d-W%02d", m.airType, m.year, m.week)
+	if suffix != "" {
+		name += "-" + suffix
+	}
+	path := name + "." + m.exportFormat
+
+	if err := exportFile(path, m.exportFormat, timetables); err != nil {
+		return m.list.NewStatusMessage(statusMessageStyle("Export failed: " + err.Error()))
+	}
+	return m.list.NewStatusMessage(statusMessageStyle(fmt.Sprintf("Exported %d shows to %s", len(timetables), path)))
+}
+
 func (m weeklyModel) loadAllAnimeForFiltering() weeklyModel {
 	// Load all anime from all days when starting to filter
-	var items []list.Item
-	for _, anime := range m.allAnime {
-		items = append(items, anime)
-	}
+	items := m.allAnimeItems()
 
 	// Update the list with all anime and set title to "All Days"
 	m.list.SetItems(items)
-	m.list.Title = "All Days"
+	m.list.Title = m.allDaysTitle()
 	m.list.SetFilteringEnabled(true)
 
 	return m
@@ -638,18 +1122,14 @@ func (m weeklyModel) loadAllAnimeForFiltering() weeklyModel {
 func (m weeklyModel) updateListBasedOnFilterState() weeklyModel {
 	if m.list.FilterState() == list.Filtering || m.list.FilterValue() != "" {
 		// When filtering, show all anime from all days
-		var items []list.Item
-		for _, anime := range m.allAnime {
-			items = append(items, anime)
-		}
+		items := m.allAnimeItems()
 		m.list.SetItems(items)
-		m.list.Title = "All Days"
+		m.list.Title = m.allDaysTitle()
 	} else {
 		// When not filtering, show only current day's anime
 		items := m.filterAnimeByDay(m.focusedDay)
 		m.list.SetItems(items)
-		dayName := fmt.Sprintf("%-9s", m.focusedDay.String())
-		m.list.Title = dayName
+		m.list.Title = m.listTitle()
 	}
 	return m
 }
@@ -692,59 +1172,44 @@ type AnimeTimetable struct {
 	AiringStatus            string      `json:"airingStatus"`
 }
 
-func fetchTimetables(apiToken string, options map[string]any) ([]AnimeTimetable, error) {
-	baseUrl := "https://animeschedule.net/api/v3/timetables"
-
-	url, err := url.Parse(baseUrl)
+func main() {
+	c, err := loadConfig(getConfigPath())
 	if err != nil {
-		return nil, fmt.Errorf("invalid base URL: %v", err)
-	}
-	if airType, ok := options["airType"].(string); ok && airType != "" {
-		url.Path += "/" + airType
+		fmt.Fprintf(os.Stderr, "baka: config: %v\n", err)
+		os.Exit(1)
 	}
+	applyConfig(c)
 
-	queryParams := url.Query()
-	if week, ok := options["week"].(int); ok && week > 0 {
-		queryParams.Add("week", fmt.Sprintf("%d", week))
-	}
-	if year, ok := options["year"].(int); ok && year > 0 {
-		queryParams.Add("year", fmt.Sprintf("%d", year))
-	}
-	if tz, ok := options["tz"].(string); ok && tz != "" {
-		queryParams.Add("tz", tz)
-	}
-	url.RawQuery = queryParams.Encode()
+	// A missing token is reported by the first request that needs it, so
+	// cached timetables still work without one
+	apiToken, _ := getEnvVariable("ANIMESCHEDULE_TOKEN")
+	client := NewAPIClient(apiToken)
 
-	req, err := http.NewRequest("GET", url.String(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+	if handled, err := runCommand(client, os.Args[1:], os.Stdout); handled {
+		// The flag package already printed the usage for -h and --help
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "baka: %s\n", friendlyError(err))
+			os.Exit(1)
+		}
+		return
 	}
 
-	req.Header.Set("Authorization", "Bearer "+apiToken)
-	req.Header.Set("Accept", "application/json")
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	res, err := client.Do(req)
+	watchlist, err := loadWatchlist()
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %v", err)
-	}
-	defer res.Body.Close()
-
-	if res.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(res.Body)
-		return nil, fmt.Errorf("API request failed: %s, response: %s", res.Status, string(body))
+		fmt.Printf("Error loading watchlist: %v", err)
+		os.Exit(1)
 	}
 
-	var result []AnimeTimetable
-	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode json response: %v", err)
+	hidden, err := loadHiddenList()
+	if err != nil {
+		fmt.Printf("Error loading hidden shows: %v", err)
+		os.Exit(1)
 	}
 
-	return result, nil
-}
-
-func main() {
-	p := tea.NewProgram(initialModel(""), tea.WithAltScreen())
+	p := tea.NewProgram(initialModel(client, watchlist, hidden), tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error running program: %v", err)
 		os.Exit(1)
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// notifyHorizon is how long after an episode started it is still worth a
+// notification, so a daemon started mid-episode still reports it but one
+// started the next day does not report yesterday's episodes.
+const notifyHorizon = time.Hour
+
+// notifyStateMaxAge is how long sent notifications are remembered.
+const notifyStateMaxAge = 14 * 24 * time.Hour
+
+var notifierKinds = []string{"auto", "dbus", "notify-send", "command"}
+
+// notification is one desktop notification about an upcoming episode.
+type notification struct {
+	title string
+	body  string
+	anime AnimeTimetable
+}
+
+// notifier delivers a notification. It is a plain function so tests can
+// swap in a fake that records what was sent.
+type notifier func(n notification) error
+
+// newNotifier returns the notifier of the given kind. auto picks D-Bus
+// when gdbus is installed and notify-send otherwise.
+func newNotifier(kind, command string) (notifier, error) {
+	if kind == "auto" {
+		kind = "notify-send"
+		if _, err := exec.LookPath("gdbus"); err == nil {
+			kind = "dbus"
+		}
+	}
+
+	switch kind {
+	case "dbus":
+		return dbusNotifier, nil
+	case "notify-send":
+		return notifySendNotifier, nil
+	case "command":
+		if command == "" {
+			return nil, errors.New("the command notifier needs notify.command or --command")
+		}
+		return commandNotifier(command), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q (want %s)", kind, strings.Join(notifierKinds, ", "))
+	}
+}
+
+// dbusNotifier calls org.freedesktop.Notifications.Notify on the session
+// bus through gdbus, which unlike dbus-send can pass the hints dictionary.
+func dbusNotifier(n notification) error {
+	cmd := exec.Command("gdbus", "call", "--session",
+		"--dest", "org.freedesktop.Notifications",
+		"--object-path", "/org/freedesktop/Notifications",
+		"--method", "org.freedesktop.Notifications.Notify",
+		"baka", "0", "", n.title, n.body, "[]", "{}", "-1")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("gdbus: %w: %s", err, out)
+	}
+	return nil
+}
+
+func notifySendNotifier(n notification) error {
+	cmd := exec.Command("notify-send", "--app-name=baka", n.title, n.body)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("notify-send: %w: %s", err, out)
+	}
+	return nil
+}
+
+// commandNotifier runs command with sh, passing the notification in
+// BAKA_TITLE, BAKA_BODY, BAKA_ROUTE, BAKA_EPISODE and BAKA_AIRS.
+func commandNotifier(command string) notifier {
+	return func(n notification) error {
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Env = append(os.Environ(),
+			"BAKA_TITLE="+n.title,
+			"BAKA_BODY="+n.body,
+			"BAKA_ROUTE="+n.anime.Route,
+			"BAKA_EPISODE="+strconv.Itoa(n.anime.EpisodeNumber),
+			"BAKA_AIRS="+n.anime.EpisodeDate.Format(time.RFC3339))
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %w: %s", command, err, out)
+		}
+		return nil
+	}
+}
+
+// notifyState remembers which episodes were already notified, so that a
+// restarted daemon does not repeat them. It is saved on every change, next
+// to the timetable cache since it is not configuration.
+type notifyState struct {
+	path string
+	sent map[string]time.Time
+}
+
+func getNotifyStatePath() string {
+	return filepath.Join(getCacheDir(), "notified.json")
+}
+
+// loadNotifyState reads the state stored at path. A missing file is an
+// empty state.
+func loadNotifyState(path string) (*notifyState, error) {
+	state := &notifyState{path: path, sent: map[string]time.Time{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &state.sent); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return state, nil
+}
+
+// episodeKey identifies an episode across timetable reloads.
+func episodeKey(anime AnimeTimetable) string {
+	return fmt.Sprintf("%s/%s/%d", anime.Route, anime.AirType, anime.EpisodeNumber)
+}
+
+// markSent records key and saves the state, forgetting entries older than
+// notifyStateMaxAge.
+func (s *notifyState) markSent(key string, now time.Time) error {
+	s.sent[key] = now
+	for k, sentAt := range s.sent {
+		if now.Sub(sentAt) > notifyStateMaxAge {
+			delete(s.sent, k)
+		}
+	}
+
+	// Create cache directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s.sent, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// notifyDaemon sends a notification lead before each watched episode. The
+// clock, the notifier and the timetable source are all fields, so it runs
+// just as well against fakes.
+type notifyDaemon struct {
+	lead     time.Duration
+	interval time.Duration
+	notify   notifier
+	state    *notifyState
+	load     func(now time.Time) ([]AnimeTimetable, error)
+	now      func() time.Time
+	after    func(d time.Duration) <-chan time.Time
+	log      io.Writer
+}
+
+// watchedEpisodes returns a loader for the watched episodes of the week of
+// now, plus the next week once lead reaches into it, so episodes early on
+// Monday are not missed.
+func watchedEpisodes(client *APIClient, airType string, lead time.Duration, watchlist, hidden *routeSet) func(now time.Time) ([]AnimeTimetable, error) {
+	return func(now time.Time) ([]AnimeTimetable, error) {
+		type isoWeek struct{ year, week int }
+		weeks := []isoWeek{}
+		for _, day := range []time.Time{now, now.Add(lead)} {
+			year, week := day.ISOWeek()
+			if len(weeks) == 0 || weeks[0] != (isoWeek{year, week}) {
+				weeks = append(weeks, isoWeek{year, week})
+			}
+		}
+
+		var episodes []AnimeTimetable
+		for _, w := range weeks {
+			timetables, err := loadTimetable(client, timetableQuery(w.year, w.week, airType))
+			if err != nil {
+				return nil, err
+			}
+			for _, anime := range timetables {
+				if watchlist.has(anime.Route) && !hidden.has(anime.Route) {
+					episodes = append(episodes, anime)
+				}
+			}
+		}
+		return episodes, nil
+	}
+}
+
+// check notifies every episode that is due and not yet notified, and
+// returns when the next episode becomes due, or the zero time if none is
+// scheduled.
+func (d *notifyDaemon) check(episodes []AnimeTimetable) time.Time {
+	now := d.now()
+
+	var next time.Time
+	for _, anime := range episodes {
+		if anime.isDelayed() {
+			continue
+		}
+
+		due := anime.EpisodeDate.Add(-d.lead)
+		if now.Before(due) {
+			if next.IsZero() || due.Before(next) {
+				next = due
+			}
+			continue
+		}
+
+		key := episodeKey(anime)
+		if _, sent := d.state.sent[key]; sent || now.Sub(anime.EpisodeDate) > notifyHorizon {
+			continue
+		}
+
+		if err := d.notify(episodeNotification(anime, now)); err != nil {
+			fmt.Fprintf(d.log, "baka: notify %s: %v\n", anime.Title, err)
+			continue
+		}
+		if err := d.state.markSent(key, now); err != nil {
+			fmt.Fprintf(d.log, "baka: notify: saving state: %v\n", err)
+		}
+	}
+	return next
+}
+
+func episodeNotification(anime AnimeTimetable, now time.Time) notification {
+	return notification{
+		title: anime.Title,
+		body: fmt.Sprintf("Episode %d (%s) %s, %s",
+			anime.EpisodeNumber,
+			anime.AirType,
+			anime.countdown(now),
+			anime.EpisodeDate.Local().Format("Mon 15:04")),
+		anime: anime,
+	}
+}
+
+// run checks for due episodes until ctx is done. It reloads the timetable
+// every interval and wakes up early when an episode becomes due.
+func (d *notifyDaemon) run(ctx context.Context) error {
+	var episodes []AnimeTimetable
+	var loadedAt time.Time
+
+	for {
+		now := d.now()
+		if loadedAt.IsZero() || now.Sub(loadedAt) >= d.interval {
+			loaded, err := d.load(now)
+			if err != nil {
+				// Keep going with the last timetable, the API may be back
+				// by the next reload
+				fmt.Fprintf(d.log, "baka: notify: %s\n", friendlyError(err))
+			} else {
+				episodes = loaded
+			}
+			loadedAt = now
+		}
+
+		wait := d.interval - d.now().Sub(loadedAt)
+		if next := d.check(episodes); !next.IsZero() {
+			wait = min(wait, next.Sub(d.now()))
+		}
+		// Never spin, even if the clock moved past next while checking
+		wait = max(wait, time.Second)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-d.after(wait):
+		}
+	}
+}
+
+func runNotify(client *APIClient, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("notify", flag.ContinueOnError)
+	lead := fs.Duration("lead", cfg.Notify.Lead, "notify this long before an episode airs")
+	kind := fs.String("notifier", cfg.Notify.Notifier, "notifier: "+strings.Join(notifierKinds, ", "))
+	command := fs.String("command", cfg.Notify.Command, "shell command for the command notifier")
+	airType := fs.String("air", cfg.API.AirType, "air type: "+strings.Join(airTypes, ", "))
+	once := fs.Bool("once", false, "send the due notifications and exit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// A bad flag would otherwise only fail every reload of the daemon
+	if *lead <= 0 {
+		return errors.New("--lead must be positive")
+	}
+	if err := oneOf(airTypes)(*airType); err != nil {
+		return fmt.Errorf("--air %s", err)
+	}
+
+	notify, err := newNotifier(*kind, *command)
+	if err != nil {
+		return err
+	}
+	state, err := loadNotifyState(getNotifyStatePath())
+	if err != nil {
+		return err
+	}
+	watchlist, err := loadWatchlist()
+	if err != nil {
+		return err
+	}
+	hidden, err := loadHiddenList()
+	if err != nil {
+		return err
+	}
+
+	d := &notifyDaemon{
+		lead:     *lead,
+		interval: cfg.Cache.TTL,
+		notify:   notify,
+		state:    state,
+		load:     watchedEpisodes(client, *airType, *lead, watchlist, hidden),
+		now:      time.Now,
+		after:    time.After,
+		log:      os.Stderr,
+	}
+
+	if *once {
+		episodes, err := d.load(d.now())
+		if err != nil {
+			return err
+		}
+		d.check(episodes)
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(stdout, "Notifying %s before episodes of %d watched shows, press Ctrl+C to stop\n",
+		formatCountdown(*lead), len(watchlist.routes))
+	return d.run(ctx)
+}
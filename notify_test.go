@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var notifyTestStart = time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+
+func testEpisode(route string, episode int, airs time.Time) AnimeTimetable {
+	return AnimeTimetable{
+		Title:         route,
+		Route:         route,
+		AirType:       "sub",
+		EpisodeNumber: episode,
+		EpisodeDate:   airs,
+	}
+}
+
+// newTestDaemon returns a daemon on a fake clock that starts at
+// notifyTestStart, and the routes it has sent notifications for.
+func newTestDaemon(t *testing.T, statePath string, clock *time.Time) (*notifyDaemon, *[]string) {
+	t.Helper()
+
+	state, err := loadNotifyState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := &[]string{}
+	*clock = notifyTestStart
+	d := &notifyDaemon{
+		lead:     15 * time.Minute,
+		interval: time.Hour,
+		notify: func(n notification) error {
+			*sent = append(*sent, n.anime.Route)
+			return nil
+		},
+		state: state,
+		now:   func() time.Time { return *clock },
+		after: func(time.Duration) <-chan time.Time {
+			t.Fatal("the daemon waited")
+			return nil
+		},
+		log: io.Discard,
+	}
+	return d, sent
+}
+
+func TestNotifyDaemonNotifiesLeadBeforeEpisode(t *testing.T) {
+	var clock time.Time
+	d, sent := newTestDaemon(t, filepath.Join(t.TempDir(), "notified.json"), &clock)
+
+	delayed := testEpisode("dungeon-meshi", 3, notifyTestStart.Add(5*time.Minute))
+	delayed.DelayedText = "Delayed"
+	episodes := []AnimeTimetable{
+		testEpisode("frieren", 5, notifyTestStart.Add(10*time.Minute)),
+		testEpisode("one-piece", 1100, notifyTestStart.Add(time.Hour)),
+		testEpisode("spy-x-family", 2, notifyTestStart.Add(-2*time.Hour)),
+		delayed,
+	}
+
+	next := d.check(episodes)
+	if want := []string{"frieren"}; !slices.Equal(*sent, want) {
+		t.Errorf("notified %v, want %v", *sent, want)
+	}
+	if want := notifyTestStart.Add(45 * time.Minute); !next.Equal(want) {
+		t.Errorf("next check at %v, want %v", next, want)
+	}
+
+	clock = next
+	d.check(episodes)
+	if want := []string{"frieren", "one-piece"}; !slices.Equal(*sent, want) {
+		t.Errorf("notified %v, want %v", *sent, want)
+	}
+}
+
+func TestNotifyDaemonDoesNotRepeatAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notified.json")
+	episodes := []AnimeTimetable{testEpisode("frieren", 5, notifyTestStart.Add(10*time.Minute))}
+
+	var clock time.Time
+	d, sent := newTestDaemon(t, path, &clock)
+	d.check(episodes)
+	if len(*sent) != 1 {
+		t.Fatalf("notified %v, want one notification", *sent)
+	}
+
+	restarted, sent := newTestDaemon(t, path, &clock)
+	clock = clock.Add(time.Minute)
+	restarted.check(episodes)
+	if len(*sent) != 0 {
+		t.Errorf("restarted daemon notified %v again", *sent)
+	}
+
+	// The next episode is a new notification
+	episodes = append(episodes, testEpisode("frieren", 6, notifyTestStart.Add(7*24*time.Hour)))
+	clock = notifyTestStart.Add(7 * 24 * time.Hour)
+	restarted.check(episodes)
+	if want := []string{"frieren"}; !slices.Equal(*sent, want) {
+		t.Errorf("notified %v, want %v", *sent, want)
+	}
+}
+
+func TestRunNotifyOnce(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+	watchlist, err := loadWatchlist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, route := range []string{"frieren", "one-piece"} {
+		if _, err := watchlist.toggle(route); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	episodes := []AnimeTimetable{
+		testEpisode("frieren", 5, now.Add(10*time.Minute)),
+		testEpisode("one-piece", 1100, now.Add(3*time.Hour)),
+		testEpisode("kusuriya-no-hitorigoto", 20, now.Add(5*time.Minute)),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(episodes)
+	}))
+	defer srv.Close()
+	client := &APIClient{BaseURL: srv.URL, Token: "token", HTTPClient: srv.Client()}
+
+	out := filepath.Join(home, "notifications")
+	args := []string{
+		"--once", "--lead", "15m", "--notifier", "command",
+		"--command", `printf '%s\n' "$BAKA_ROUTE" >> '` + out + `'`,
+	}
+
+	// The second run must remember the first one's notifications
+	for range 2 {
+		if err := runNotify(client, args, io.Discard); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(string(data)); !slices.Equal(got, []string{"frieren"}) {
+		t.Errorf("notified %v, want [frieren]", got)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".cache", "baka", "notified.json")); err != nil {
+		t.Errorf("notification state not in the cache directory: %v", err)
+	}
+}
+
+func TestRunNotifyRejectsBadFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"--air", "foo"},
+		{"--lead", "0s"},
+		{"--lead", "-5m"},
+		{"--notifier", "pager"},
+	} {
+		if err := runNotify(nil, args, io.Discard); err == nil {
+			t.Errorf("runNotify(%q) started, want an error", args)
+		}
+	}
+}
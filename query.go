@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AirType selects which releases the timetable lists.
+type AirType string
+
+const (
+	AirTypeSub AirType = "sub"
+	AirTypeDub AirType = "dub"
+	AirTypeRaw AirType = "raw"
+	AirTypeAll AirType = "all"
+)
+
+// airTypes are the timetable variants served by animeschedule.net, in the
+// order the air type toggle cycles through them.
+var airTypes = []string{string(AirTypeSub), string(AirTypeDub), string(AirTypeRaw), string(AirTypeAll)}
+
+func nextAirType(current string) string {
+	for i, airType := range airTypes {
+		if airType == current {
+			return airTypes[(i+1)%len(airTypes)]
+		}
+	}
+	return airTypes[0]
+}
+
+// TimetableQuery holds every parameter of the v3 timetables endpoint. Week
+// and Year default to the current week when zero, and an empty TZ leaves
+// the API on its own default. TimetableQuery is comparable, so it doubles
+// as the identity of a loaded timetable.
+type TimetableQuery struct {
+	AirType AirType
+	Week    int
+	Year    int
+	TZ      string
+}
+
+// Validate reports the first parameter the API would reject or ignore.
+func (q TimetableQuery) Validate() error {
+	if q.AirType != "" && !slices.Contains(airTypes, string(q.AirType)) {
+		return fmt.Errorf("invalid timetable query: air type %q, want one of %s", q.AirType, strings.Join(airTypes, ", "))
+	}
+	if q.Week < 0 || q.Week > 53 {
+		return fmt.Errorf("invalid timetable query: week %d, want 1-53", q.Week)
+	}
+	if q.Year != 0 && (q.Year < 2000 || q.Year > 9999) {
+		return fmt.Errorf("invalid timetable query: year %d, want 2000-9999", q.Year)
+	}
+	if q.Week == 53 && q.Year != 0 {
+		// Only years starting or ending on a Thursday have a 53rd ISO week
+		if year, week := weekStart(q.Year, 53).ISOWeek(); year != q.Year || week != 53 {
+			return fmt.Errorf("invalid timetable query: %d has no ISO week 53", q.Year)
+		}
+	}
+	if q.TZ != "" {
+		if _, err := time.LoadLocation(q.TZ); err != nil {
+			return fmt.Errorf("invalid timetable query: unknown IANA timezone %q", q.TZ)
+		}
+	}
+	return nil
+}
+
+// path returns the endpoint path for the query, relative to the API root.
+func (q TimetableQuery) path() string {
+	if q.AirType == "" {
+		return "/timetables"
+	}
+	return "/timetables/" + string(q.AirType)
+}
+
+func (q TimetableQuery) values() url.Values {
+	values := url.Values{}
+	if q.Week > 0 {
+		values.Set("week", strconv.Itoa(q.Week))
+	}
+	if q.Year > 0 {
+		values.Set("year", strconv.Itoa(q.Year))
+	}
+	if q.TZ != "" {
+		values.Set("tz", q.TZ)
+	}
+	return values
+}
+
+// timetableQuery is the query for a week and air type in the user's
+// timezone.
+func timetableQuery(year, week int, airType string) TimetableQuery {
+	return TimetableQuery{
+		AirType: AirType(airType),
+		Week:    week,
+		Year:    year,
+		TZ:      getSystemTimezone(),
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// searchDebounce is how long typing has to pause before a search is sent.
+const searchDebounce = 300 * time.Millisecond
+
+type animeSearchResponse struct {
+	Page        int     `json:"page"`
+	TotalAmount int     `json:"totalAmount"`
+	Anime       []Anime `json:"anime"`
+}
+
+// SearchAnime searches every anime on animeschedule.net by title.
+func (c *APIClient) SearchAnime(text string) ([]Anime, error) {
+	u, err := c.endpoint("/anime")
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = url.Values{"q": {text}}.Encode()
+
+	res, err := c.do(u, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var body animeSearchResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return body.Anime, nil
+}
+
+// timetableEntry returns the fields anime shares with a timetable entry, so
+// that search results can use the detail view.
+func (a Anime) timetableEntry() AnimeTimetable {
+	return AnimeTimetable{
+		Title:      a.Title,
+		Route:      a.Route,
+		Romaji:     a.Names.Romaji,
+		English:    a.Names.English,
+		Native:     a.Names.Native,
+		Status:     a.Status,
+		Episodes:   a.Episodes,
+		LengthMin:  a.LengthMin,
+		MediaTypes: a.MediaTypes,
+	}
+}
+
+type searchItem struct {
+	anime   Anime
+	watched bool
+}
+
+func (i searchItem) Title() string {
+	title := i.anime.Title
+	if i.watched {
+		title = "★ " + title
+	}
+	return title
+}
+
+func (i searchItem) Description() string {
+	parts := []string{}
+	if i.anime.Season.Title != "" {
+		parts = append(parts, i.anime.Season.Title)
+	}
+	if i.anime.Status != "" {
+		parts = append(parts, i.anime.Status)
+	}
+	if genres := categoryNames(i.anime.Genres); genres != "" {
+		parts = append(parts, genres)
+	}
+	return strings.Join(parts, " • ")
+}
+
+func (i searchItem) FilterValue() string { return i.anime.Title }
+
+// newSearchDelegate links search results into the detail view and the
+// watchlist with the same keys as the weekly list.
+func newSearchDelegate(keys *delegateKeyMap, watchlist *routeSet) list.DefaultDelegate {
+	d := list.NewDefaultDelegate()
+	d.SetSpacing(1)
+	d.Styles.SelectedTitle = lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	d.Styles.NormalTitle = lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+
+	d.UpdateFunc = func(msg tea.Msg, m *list.Model) tea.Cmd {
+		i, ok := m.SelectedItem().(searchItem)
+		if !ok {
+			return nil
+		}
+
+		keyMsg, ok := msg.(tea.KeyMsg)
+		if !ok {
+			return nil
+		}
+
+		switch {
+		case key.Matches(keyMsg, keys.choose):
+			item := animeItem{anime: i.anime.timetableEntry(), watched: i.watched}
+			return func() tea.Msg { return showDetailMsg{item: item} }
+
+		case key.Matches(keyMsg, keys.watch):
+			watched, ok, status := toggleWatch(m, watchlist, i.anime.Route, i.anime.Title)
+			if !ok {
+				return status
+			}
+			i.watched = watched
+			// The weekly list shows the watched star too
+			return tea.Batch(
+				m.SetItem(m.GlobalIndex(), i),
+				func() tea.Msg { return routesChangedMsg{} },
+				status,
+			)
+		}
+		return nil
+	}
+
+	help := []key.Binding{keys.choose, keys.watch}
+	d.ShortHelpFunc = func() []key.Binding { return help }
+	d.FullHelpFunc = func() [][]key.Binding { return [][]key.Binding{help} }
+
+	return d
+}
+
+// searchModel is the global search overlay. Typing goes to input, tab
+// moves the focus to the results.
+type searchModel struct {
+	keys         *listKeyMap
+	delegateKeys *delegateKeyMap
+	input        textinput.Model
+	results      list.Model
+	browsing     bool
+	seq          int
+	text         string
+	searching    bool
+	err          error
+}
+
+// searchDebounceMsg fires searchDebounce after keystroke seq.
+type searchDebounceMsg struct {
+	seq int
+}
+
+type searchResultsMsg struct {
+	text    string
+	results []Anime
+	err     error
+}
+
+func newSearchModel(keys *listKeyMap, delegateKeys *delegateKeyMap, watchlist *routeSet, width, height int) *searchModel {
+	input := textinput.New()
+	input.Placeholder = "Search all anime"
+	input.Prompt = "Search: "
+	input.Focus()
+
+	results := list.New([]list.Item{}, newSearchDelegate(delegateKeys, watchlist), width, height)
+	setListKeys(&results.KeyMap)
+	results.SetShowTitle(false)
+	results.SetFilteringEnabled(false)
+	results.SetShowHelp(false)
+	results.DisableQuitKeybindings()
+
+	s := &searchModel{keys: keys, delegateKeys: delegateKeys, input: input, results: results}
+	s.setSize(width, height)
+	return s
+}
+
+func (s *searchModel) setSize(width, height int) {
+	s.input.Width = min(width-len(s.input.Prompt)-4, 60)
+	// The input, status and help lines sit above and below the results
+	s.results.SetSize(width, height-6)
+}
+
+func searchAnimeCmd(client *APIClient, text string) tea.Cmd {
+	return func() tea.Msg {
+		results, err := client.SearchAnime(text)
+		return searchResultsMsg{text: text, results: results, err: err}
+	}
+}
+
+// debounce schedules a search for the current input once typing pauses.
+func (s *searchModel) debounce() tea.Cmd {
+	s.seq++
+	seq := s.seq
+	return tea.Tick(searchDebounce, func(time.Time) tea.Msg {
+		return searchDebounceMsg{seq: seq}
+	})
+}
+
+// search sends the current input unless it was already sent.
+func (s *searchModel) search(client *APIClient) tea.Cmd {
+	text := strings.TrimSpace(s.input.Value())
+	if text == s.text {
+		return nil
+	}
+	s.text = text
+	s.err = nil
+	if text == "" {
+		s.searching = false
+		return s.results.SetItems(nil)
+	}
+	s.searching = true
+	return searchAnimeCmd(client, text)
+}
+
+func (m weeklyModel) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := m.search
+
+	// Letters go to the input while typing, even those bound to actions
+	typing := !s.browsing && msg.Type == tea.KeyRunes && !msg.Alt
+
+	switch {
+	case typing:
+	case key.Matches(msg, s.keys.back):
+		if s.browsing {
+			s.browsing = false
+			s.input.Focus()
+			return m, nil
+		}
+		m.search = nil
+		return m, nil
+	case key.Matches(msg, s.keys.focus):
+		s.browsing = !s.browsing && len(s.results.Items()) > 0
+		if s.browsing {
+			s.input.Blur()
+		} else {
+			s.input.Focus()
+		}
+		return m, nil
+	}
+
+	if s.browsing {
+		var cmd tea.Cmd
+		s.results, cmd = s.results.Update(msg)
+		return m, cmd
+	}
+
+	switch {
+	case typing:
+	case key.Matches(msg, s.delegateKeys.choose):
+		// Search right away, or browse the results if they are current
+		if cmd := s.search(m.client); cmd != nil {
+			return m, cmd
+		}
+		if !s.searching && len(s.results.Items()) > 0 {
+			s.browsing = true
+			s.input.Blur()
+		}
+		return m, nil
+	case key.Matches(msg, s.results.KeyMap.CursorUp, s.results.KeyMap.CursorDown):
+		var cmd tea.Cmd
+		s.results, cmd = s.results.Update(msg)
+		return m, cmd
+	}
+
+	previous := s.input.Value()
+	var cmd tea.Cmd
+	s.input, cmd = s.input.Update(msg)
+	if s.input.Value() != previous {
+		return m, tea.Batch(cmd, s.debounce())
+	}
+	return m, cmd
+}
+
+func (m weeklyModel) searchResultItems(results []Anime) []list.Item {
+	items := make([]list.Item, len(results))
+	for i, anime := range results {
+		items[i] = searchItem{anime: anime, watched: m.watchlist.has(anime.Route)}
+	}
+	return items
+}
+
+func (s *searchModel) View(width int) string {
+	status := ""
+	switch {
+	case s.searching:
+		status = "Searching…"
+	case s.err != nil:
+		status = friendlyError(s.err)
+	case s.text != "":
+		noun := "results"
+		if len(s.results.Items()) == 1 {
+			noun = "result"
+		}
+		status = fmt.Sprintf("%d %s for %q", len(s.results.Items()), noun, s.text)
+	}
+
+	help := fmt.Sprintf("type to search • %s: browse results • %s: close",
+		firstKeyHelp(s.keys.focus), firstKeyHelp(s.keys.back))
+	if s.browsing {
+		help = fmt.Sprintf("%s: details • %s: watch • %s/%s: back to search",
+			firstKeyHelp(s.delegateKeys.choose), firstKeyHelp(s.delegateKeys.watch),
+			firstKeyHelp(s.keys.focus), firstKeyHelp(s.keys.back))
+	}
+
+	faint := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	return lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render("Search animeschedule.net"),
+		"",
+		s.input.View(),
+		faint.Render(status),
+		s.results.View(),
+		faint.Width(width).Align(lipgloss.Center).Render(help),
+	)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// opener opens a URL outside the terminal, usually in the browser. It is a
+// plain function so tests can swap in a fake that records the URLs.
+type opener func(url string) error
+
+// showStreamsMsg asks the weekly model to list the streams of an item.
+type showStreamsMsg struct {
+	item animeItem
+}
+
+type streamOpenedMsg struct {
+	link streamLink
+	err  error
+}
+
+var selectedStreamStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+
+// defaultOpener runs the command in $BAKA_OPENER, falling back to the
+// platform's own URL handler.
+func defaultOpener() opener {
+	if command := strings.Fields(os.Getenv("BAKA_OPENER")); len(command) > 0 {
+		return commandOpener(command[0], command[1:]...)
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return commandOpener("open")
+	case "windows":
+		return commandOpener("rundll32", "url.dll,FileProtocolHandler")
+	default:
+		return commandOpener("xdg-open")
+	}
+}
+
+// commandOpener returns an opener that runs name with args and the URL
+// appended as the last argument.
+func commandOpener(name string, args ...string) opener {
+	return func(url string) error {
+		cmd := exec.Command(name, append(args, url)...)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	}
+}
+
+// streamURL makes sure a stream link has a scheme, the API sometimes
+// returns bare hostnames.
+func streamURL(raw string) string {
+	if strings.Contains(raw, "://") {
+		return raw
+	}
+	return "https://" + strings.TrimPrefix(raw, "//")
+}
+
+// streamPickerHint lists the keys of the stream picker.
+func (m weeklyModel) streamPickerHint() string {
+	selectKeys := firstKeyHelp(m.keys.up) + firstKeyHelp(m.keys.down)
+	if numbers := m.streamNumberKeys(); len(numbers) > 0 {
+		selectKeys += "/" + keyRangeHelp(numbers)
+	}
+	return fmt.Sprintf("%s: select • %s: open • %s: cancel",
+		selectKeys, firstKeyHelp(m.delegateKeys.choose), firstKeyHelp(m.keys.back))
+}
+
+// streamNumberKeys returns the number keys that open a provider of the
+// picker directly, leaving out those bound to another picker key.
+func (m weeklyModel) streamNumberKeys() []string {
+	var numbers []string
+	for i := 1; i <= min(len(m.picker.links), 9); i++ {
+		number := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{rune('0' + i)}}
+		if !key.Matches(number, m.keys.back, m.keys.quit, m.keys.up, m.keys.down, m.delegateKeys.choose) {
+			numbers = append(numbers, number.String())
+		}
+	}
+	return numbers
+}
+
+func openStreamCmd(open opener, link streamLink) tea.Cmd {
+	return func() tea.Msg {
+		return streamOpenedMsg{link: link, err: open(streamURL(link.url))}
+	}
+}
+
+// renderStreamPicker renders the numbered provider list with the cursor on
+// the selected entry, followed by the hint for keys.
+func renderStreamPicker(title string, links []streamLink, cursor int, hint string) string {
+	var b strings.Builder
+	b.WriteString(detailTitleStyle.Render("Open "+strings.TrimSpace(title)) + "\n\n")
+	for i, link := range links {
+		line := fmt.Sprintf("%d. %s", i+1, link.provider)
+		if i == cursor {
+			line = selectedStreamStyle.Render("> " + line)
+		} else {
+			line = "  " + line
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n" + detailLabelStyle.Width(0).Render(hint))
+	return detailStyle.Render(b.String())
+}
+
+// streamPicker is the modal list of stream providers for one show.
+type streamPicker struct {
+	title  string
+	links  []streamLink
+	cursor int
+}
+
+// updateStreamPicker handles keys while the stream picker is open. Quit
+// only closes the picker.
+func (m weeklyModel) updateStreamPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.picker
+	switch {
+	case key.Matches(msg, m.keys.back, m.keys.quit):
+		m.picker = nil
+	case key.Matches(msg, m.keys.up):
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case key.Matches(msg, m.keys.down):
+		if p.cursor < len(p.links)-1 {
+			p.cursor++
+		}
+	case key.Matches(msg, m.delegateKeys.choose):
+		m.picker = nil
+		return m, openStreamCmd(m.open, p.links[p.cursor])
+	default:
+		// Number keys open a provider directly
+		if s := msg.String(); len(s) == 1 && s[0] >= '1' && int(s[0]-'0') <= len(p.links) {
+			m.picker = nil
+			return m, openStreamCmd(m.open, p.links[s[0]-'1'])
+		}
+	}
+	return m, nil
+}
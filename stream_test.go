@@ -0,0 +1,57 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestStreamPickerOpensSelectedLink(t *testing.T) {
+	links := []streamLink{
+		{provider: "Crunchyroll", url: "www.crunchyroll.com/frieren"},
+		{provider: "Netflix", url: "https://www.netflix.com/title/81726714"},
+	}
+	tests := []struct {
+		name string
+		keys []tea.KeyMsg
+		want string
+	}{
+		{"number", []tea.KeyMsg{{Type: tea.KeyRunes, Runes: []rune("2")}}, "https://www.netflix.com/title/81726714"},
+		{"enter", []tea.KeyMsg{{Type: tea.KeyEnter}}, "https://www.crunchyroll.com/frieren"},
+		{"down and enter", []tea.KeyMsg{{Type: tea.KeyDown}, {Type: tea.KeyEnter}}, "https://www.netflix.com/title/81726714"},
+	}
+
+	watchlist, err := loadRouteSet(filepath.Join(t.TempDir(), "watchlist.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		var opened []string
+		m := initialModel(nil, watchlist, watchlist)
+		m.open = func(url string) error {
+			opened = append(opened, url)
+			return nil
+		}
+		m.picker = &streamPicker{title: "Frieren", links: links}
+
+		var model tea.Model = m
+		var cmd tea.Cmd
+		for _, msg := range tt.keys {
+			model, cmd = model.(weeklyModel).updateStreamPicker(msg)
+		}
+		if model.(weeklyModel).picker != nil || cmd == nil {
+			t.Fatalf("%s: picker still open", tt.name)
+		}
+
+		msg, ok := cmd().(streamOpenedMsg)
+		if !ok || msg.err != nil {
+			t.Fatalf("%s: command returned %+v, want a streamOpenedMsg", tt.name, msg)
+		}
+		if !slices.Equal(opened, []string{tt.want}) {
+			t.Errorf("%s: opened %q, want %q", tt.name, opened, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// routeSet is a set of anime routes that is persisted to disk as a JSON
+// array every time it changes.
+type routeSet struct {
+	path   string
+	routes map[string]bool
+}
+
+func getConfigFilePath(name string) string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return name // fallback to current directory
+	}
+	return filepath.Join(configDir, "baka", name)
+}
+
+// loadRouteSet reads the set stored at path. A missing file is an empty set.
+func loadRouteSet(path string) (*routeSet, error) {
+	set := &routeSet{path: path, routes: map[string]bool{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return set, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []string
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, err
+	}
+	for _, route := range routes {
+		set.routes[route] = true
+	}
+
+	return set, nil
+}
+
+func (s *routeSet) has(route string) bool {
+	return s.routes[route]
+}
+
+// toggle adds or removes route and saves the set, reporting whether the
+// route is now a member. The set is left as it was if saving fails, so it
+// never shows a change that is lost on restart.
+func (s *routeSet) toggle(route string) (bool, error) {
+	was := s.routes[route]
+	s.set(route, !was)
+	if err := s.save(); err != nil {
+		s.set(route, was)
+		return was, err
+	}
+	return !was, nil
+}
+
+func (s *routeSet) set(route string, member bool) {
+	if member {
+		s.routes[route] = true
+	} else {
+		delete(s.routes, route)
+	}
+}
+
+func (s *routeSet) save() error {
+	routes := make([]string, 0, len(s.routes))
+	for route := range s.routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	// Create config directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(routes, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// loadWatchlist loads the routes of the shows the user follows.
+func loadWatchlist() (*routeSet, error) {
+	return loadRouteSet(getConfigFilePath("watchlist.json"))
+}
+
+// loadHiddenList loads the routes of the shows the user has hidden.
+func loadHiddenList() (*routeSet, error) {
+	return loadRouteSet(getConfigFilePath("hidden.json"))
+}